@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tcornell05/go/tr1p-cycle/internal/ipc"
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags] [command]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Without a command the cycler starts. Commands control a running instance:")
+	fmt.Fprintln(out, "  add        add the active window to the ring")
+	fmt.Fprintln(out, "  remove     remove the active window from the ring")
+	fmt.Fprintln(out, "  next       focus the next window in the ring")
+	fmt.Fprintln(out, "  prev       focus the previous window in the ring")
+	fmt.Fprintln(out, "  list       print the ring, marking the current window with *")
+	fmt.Fprintln(out, "  focus <n>  focus the window in slot n, counting from 1")
+	fmt.Fprintln(out, "  save       write the rings to their state files")
+	fmt.Fprintln(out, "  quit       stop the running instance")
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+// runCommand sends a command to the running instance and returns the exit
+// status.
+func runCommand(args []string) int {
+	req := ipc.Request{Command: args[0], Ring: ringName}
+	switch args[0] {
+	case "add", "remove", "next", "prev", "list", "save", "quit":
+		if len(args) != 1 {
+			return usageError("%s takes no arguments", args[0])
+		}
+	case "focus":
+		if len(args) != 2 {
+			return usageError("focus takes a slot number")
+		}
+		slot, err := strconv.Atoi(args[1])
+		if err != nil || slot < 1 {
+			return usageError("invalid slot %q", args[1])
+		}
+		req.Index = slot - 1
+	default:
+		return usageError("unknown command %q", args[0])
+	}
+
+	resp, err := ipc.Send(socketPath, req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if req.Command == "list" {
+		for i, item := range resp.Items {
+			mark := " "
+			if item.Current {
+				mark = "*"
+			}
+			title := item.Title
+			if item.Window == 0 {
+				title += " (not open)"
+			}
+			fmt.Printf("%s %d  %s  [%s]\n", mark, i+1, title, item.AppName)
+		}
+	}
+	return 0
+}
+
+func usageError(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	flag.Usage()
+	return 2
+}
@@ -2,44 +2,131 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
 
 	"fyne.io/fyne/v2/app"
+	"github.com/godbus/dbus/v5"
 	"github.com/tcornell05/go/tr1p-cycle/internal/cycle"
+	"github.com/tcornell05/go/tr1p-cycle/internal/dbusapi"
+	"github.com/tcornell05/go/tr1p-cycle/internal/ipc"
 )
 
-var debug bool
+var (
+	debug      bool
+	backend    string
+	configPath string
+	socketPath string
+	ringName   string
+)
 
 func main() {
 	flag.BoolVar(&debug, "debug", false, "enable debug mode")
+	flag.StringVar(&backend, "backend", "x11", "window backend to use: x11 or exec (xdotool/wmctrl)")
+	flag.StringVar(&configPath, "config", "", "path to config file (default $XDG_CONFIG_HOME/tr1p-cycle/config.toml)")
+	flag.StringVar(&socketPath, "socket", ipc.DefaultSocketPath(), "path to the control socket")
+	flag.StringVar(&ringName, "ring", "", "ring for commands (default the active ring)")
+	flag.Usage = usage
 	flag.Parse()
 
+	if flag.NArg() > 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+
 	if debug {
 		log.SetOutput(os.Stdout)
 	} else {
 		log.SetOutput(io.Discard) // Discard all logs when not in debug mode
 	}
 
+	cfg, err := loadConfig()
+	if err != nil {
+		// Config mistakes should be visible even without -debug.
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	myApp := app.New()
 
-	defaultKeybinds := cycle.Keybinds{
-		AddKeybind:    "Alt+Shift+E",
-		RemoveKeybind: "Alt+Shift+D",
-		CycleKeybind:  "Alt+Tab",
+	var wb cycle.WindowBackend
+	switch backend {
+	case "x11":
+		xb, err := cycle.NewX11Backend()
+		if err != nil {
+			log.Fatalf("Failed to create X11 backend: %v", err)
+		}
+		defer xb.Close()
+		wb = xb
+	case "exec":
+		wb = cycle.NewExecBackend()
+	default:
+		log.Fatalf("Unknown backend: %s", backend)
 	}
 
-	cl := cycle.NewCycleList()
-	preview := cycle.NewPreview(myApp, cl)
+	rings := cycle.NewRings(wb, cfg)
+	if cfg.List.Persist {
+		restore(rings, cfg.List.StateFile)
+		defer rings.Flush()
+	}
+	cycle.StartHooks(rings, cfg.Hooks)
+	preview := cycle.NewPreview(myApp, rings, cfg.Preview)
+
+	switcher := cycle.NewSwitcher(myApp, rings, cfg.Preview)
 
-	listener, err := cycle.NewKeybindListener(cl, defaultKeybinds, preview)
+	listener, err := cycle.NewKeybindListener(rings, cfg, preview, switcher)
 	if err != nil {
 		log.Fatalf("Failed to create keybind listener: %v", err)
 	}
 
-	go cl.MonitorActiveWindow()
+	go rings.MonitorActiveWindow()
+	defer rings.StopMonitor()
 	go listener.Listen()
 
+	ctl := cycle.NewController(rings, preview, myApp.Quit)
+	server, err := ipc.Listen(socketPath, ctl)
+	if err != nil {
+		log.Printf("Control socket disabled: %v", err)
+	} else {
+		go server.Serve()
+		defer server.Close()
+	}
+
+	if conn, err := dbus.ConnectSessionBus(); err != nil {
+		log.Printf("D-Bus interface disabled: %v", err)
+	} else {
+		defer conn.Close()
+		if err := dbusapi.Export(conn, ctl, rings); err != nil {
+			log.Printf("D-Bus interface disabled: %v", err)
+		}
+	}
+
 	myApp.Run()
 }
+
+func loadConfig() (cycle.Config, error) {
+	if configPath != "" {
+		return cycle.LoadConfig(configPath, true)
+	}
+	path, err := cycle.DefaultConfigPath()
+	if err != nil {
+		log.Printf("Using default config: %v", err)
+		return cycle.DefaultConfig(), nil
+	}
+	return cycle.LoadConfig(path, false)
+}
+
+func restore(rings *cycle.Rings, storePath string) {
+	if storePath == "" {
+		var err error
+		storePath, err = cycle.DefaultStorePath()
+		if err != nil {
+			log.Printf("Cycle list will not be saved: %v", err)
+			return
+		}
+	}
+	if err := rings.Restore(storePath); err != nil {
+		log.Printf("Failed to restore cycle lists: %v", err)
+	}
+}
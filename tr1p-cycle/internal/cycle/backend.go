@@ -0,0 +1,100 @@
+package cycle
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+)
+
+// WindowID identifies a top-level X window.
+type WindowID uint32
+
+func (id WindowID) String() string {
+	return fmt.Sprintf("0x%08x", uint32(id))
+}
+
+// parseWindowID accepts both the decimal form printed by xdotool and the
+// hex form printed by wmctrl.
+func parseWindowID(s string) (WindowID, error) {
+	id, err := strconv.ParseUint(s, 0, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window ID %q: %v", s, err)
+	}
+	return WindowID(id), nil
+}
+
+// AllDesktops is the Desktop of windows shown on every desktop, or whose
+// desktop is unknown.
+const AllDesktops = -1
+
+type WindowInfo struct {
+	ID      WindowID
+	Title   string
+	PID     int
+	AppName string
+	Class   string // class part of WM_CLASS
+	Desktop int    // zero-based, or AllDesktops
+	// Type is the lowercase _NET_WM_WINDOW_TYPE suffix, e.g. "normal" or
+	// "dock". Empty when the backend can't tell.
+	Type  string
+	State WindowState
+}
+
+// WindowState holds the _NET_WM_STATE flags that matter when cycling. It is
+// zero when the backend can't tell.
+type WindowState uint8
+
+const (
+	StateHidden WindowState = 1 << iota // minimized
+	StateFullscreen
+	StateMaximized // both vertically and horizontally
+	StateDemandsAttention
+)
+
+func (s WindowState) Has(flag WindowState) bool {
+	return s&flag != 0
+}
+
+// WindowRestorer is implemented by backends that can bring a minimized
+// window back before focusing it, rather than leaving that to the window
+// manager's handling of focus requests.
+type WindowRestorer interface {
+	Restore(id WindowID) error
+}
+
+// WindowBackend is everything CycleList needs from the window system.
+type WindowBackend interface {
+	ActiveWindow() (WindowInfo, error)
+	ListWindows() ([]WindowInfo, error)
+	Focus(id WindowID) error
+	WindowInfo(id WindowID) (WindowInfo, error)
+}
+
+type WindowEvent int
+
+const (
+	ActiveWindowChanged WindowEvent = iota
+	ClientListChanged
+)
+
+// WindowEventSource is implemented by backends that can report changes as
+// they happen instead of being polled. The returned channel is closed once
+// stop is closed or the backend loses its connection.
+type WindowEventSource interface {
+	WatchWindows(stop <-chan struct{}) (<-chan WindowEvent, error)
+}
+
+// WindowCapturer is implemented by backends that can grab what a window is
+// showing. The image is scaled down to fit within maxWidth x maxHeight.
+// ReleaseWindow frees whatever CaptureWindow set up for the window once no
+// more captures of it are wanted.
+type WindowCapturer interface {
+	CaptureWindow(id WindowID, maxWidth, maxHeight int) (image.Image, error)
+	ReleaseWindow(id WindowID)
+}
+
+// WindowIconSource is implemented by backends that can read the icon a
+// window advertises. The icon closest to size pixels is returned.
+type WindowIconSource interface {
+	WindowIcon(id WindowID, size int) (image.Image, error)
+}
@@ -0,0 +1,432 @@
+package cycle
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+type Config struct {
+	// Keybinds drive the default ring.
+	Keybinds Keybinds      `toml:"keybinds"`
+	Rings    []RingConfig  `toml:"rings"`
+	Rules    []RuleConfig  `toml:"rules"`
+	Exclude  []RuleConfig  `toml:"exclude"`
+	Preview  PreviewConfig `toml:"preview"`
+	Timing   TimingConfig  `toml:"timing"`
+	List     ListConfig    `toml:"list"`
+	Hooks    HooksConfig   `toml:"hooks"`
+}
+
+// DefaultRingName is the ring driven by the [keybinds] section.
+const DefaultRingName = "default"
+
+type Keybinds struct {
+	AddKeybind     string `toml:"add"`
+	RemoveKeybind  string `toml:"remove"`
+	CycleKeybind   string `toml:"cycle"`
+	ReverseKeybind string `toml:"reverse"`
+	// MoveKeybind moves the focused window into this ring, taking it out
+	// of every other ring.
+	MoveKeybind string `toml:"move"`
+	// SlotModifiers are combined with the digits 1-9 to jump straight to a
+	// slot, e.g. "Alt" gives Alt+1 .. Alt+9. Empty, the default, disables
+	// slot hotkeys.
+	SlotModifiers string `toml:"slot_modifiers"`
+	// MoveUpKeybind and MoveDownKeybind shift the current item one slot
+	// within the active ring. Both are off by default.
+	MoveUpKeybind   string `toml:"move_up"`
+	MoveDownKeybind string `toml:"move_down"`
+	// SwitcherKeybind opens a search over every open window, not just the
+	// ones in a ring. It is off by default.
+	SwitcherKeybind string `toml:"switcher"`
+}
+
+// SlotKeybinds returns the keybind for each slot, or nil when slot hotkeys
+// are disabled.
+func (k Keybinds) SlotKeybinds() []string {
+	if k.SlotModifiers == "" {
+		return nil
+	}
+	binds := make([]string, MaxSlots)
+	for i := range binds {
+		binds[i] = fmt.Sprintf("%s+%d", k.SlotModifiers, i+1)
+	}
+	return binds
+}
+
+// RingConfig defines an additional named ring. All of its keybinds are
+// optional.
+type RingConfig struct {
+	Name string `toml:"name"`
+	Keybinds
+}
+
+type PreviewConfig struct {
+	Width  float32 `toml:"width"`
+	Height float32 `toml:"height"`
+	// Colors are "#rrggbb" or "#rrggbbaa". An empty background uses the
+	// theme background.
+	Background    string `toml:"background"`
+	Highlight     string `toml:"highlight"`
+	HighlightText string `toml:"highlight_text"`
+	// Layout is "list" for one row per item or "grid" for thumbnails in a
+	// grid, which implies Thumbnails.
+	Layout string `toml:"layout"`
+	// Thumbnails shows a live capture of each window, scaled to fit within
+	// ThumbnailWidth x ThumbnailHeight and recaptured every
+	// ThumbnailRefresh while the preview is shown.
+	Thumbnails       bool          `toml:"thumbnails"`
+	ThumbnailWidth   float32       `toml:"thumbnail_width"`
+	ThumbnailHeight  float32       `toml:"thumbnail_height"`
+	ThumbnailRefresh time.Duration `toml:"thumbnail_refresh"`
+	// Icons shows each application's icon left of its title.
+	Icons    bool    `toml:"icons"`
+	IconSize float32 `toml:"icon_size"`
+}
+
+const (
+	LayoutList = "list"
+	LayoutGrid = "grid"
+)
+
+type TimingConfig struct {
+	// CycleDebounce ignores repeated cycle presses closer together than this.
+	CycleDebounce time.Duration `toml:"cycle_debounce"`
+	// ModifierPoll is how often the keyboard is checked for the cycle
+	// modifier being released.
+	ModifierPoll time.Duration `toml:"modifier_poll"`
+	// ActiveWindowPoll is only used by backends without window events.
+	ActiveWindowPoll time.Duration `toml:"active_window_poll"`
+}
+
+const (
+	InsertAfterCurrent = "after_current"
+	InsertAtEnd        = "end"
+)
+
+const (
+	OrderRing = "ring"
+	OrderMRU  = "mru"
+)
+
+const (
+	WorkspaceCurrent = "current"
+	WorkspaceSwitch  = "switch"
+	WorkspaceBring   = "bring"
+)
+
+const (
+	MinimizedRestore = "restore"
+	MinimizedSkip    = "skip"
+)
+
+type ListConfig struct {
+	// Insert is where Add places new windows: "after_current" or "end".
+	// It is ignored in MRU order, where new windows go to the front.
+	Insert string `toml:"insert"`
+	// Order is "ring" for a fixed order or "mru" to keep the most recently
+	// used window first.
+	Order   string `toml:"order"`
+	Persist bool   `toml:"persist"`
+	// Ghosts keeps closed windows in the ring, greyed out, until the
+	// application is started again instead of removing them.
+	Ghosts bool `toml:"ghosts"`
+	// PerMonitor makes cycling skip windows that aren't on the same monitor
+	// as the active window.
+	PerMonitor bool `toml:"per_monitor"`
+	// Workspace is how windows on other workspaces are treated: "current"
+	// cycles only through the current workspace, "switch" switches to the
+	// window's workspace and "bring" moves the window to the current one.
+	Workspace string `toml:"workspace"`
+	// Minimized is "restore" to cycle onto minimized windows and restore
+	// them, or "skip" to pass over them.
+	Minimized string `toml:"minimized"`
+	// StateFile overrides DefaultStorePath. A leading "~/" is expanded.
+	StateFile string `toml:"state_file"`
+}
+
+// HooksConfig holds shell commands run when a window is added to a ring,
+// removed from one, or focused by cycling or a slot. See StartHooks.
+type HooksConfig struct {
+	OnAdd    string `toml:"on_add"`
+	OnRemove string `toml:"on_remove"`
+	OnFocus  string `toml:"on_focus"`
+	// Timeout kills hooks that run longer than this.
+	Timeout time.Duration `toml:"timeout"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Keybinds: Keybinds{
+			AddKeybind:     "Alt+Shift+E",
+			RemoveKeybind:  "Alt+Shift+D",
+			CycleKeybind:   "Alt+Tab",
+			ReverseKeybind: "Alt+Shift+Tab",
+		},
+		// Panels and the desktop are never worth cycling to.
+		Exclude: []RuleConfig{
+			{Type: "dock"},
+			{Type: "desktop"},
+		},
+		Preview: PreviewConfig{
+			Width:            500,
+			Height:           400,
+			Highlight:        "#90ee90", // Light Green
+			HighlightText:    "#00008b", // Dark Blue
+			Layout:           LayoutList,
+			ThumbnailWidth:   160,
+			ThumbnailHeight:  100,
+			ThumbnailRefresh: time.Second,
+			Icons:            true,
+			IconSize:         24,
+		},
+		Timing: TimingConfig{
+			CycleDebounce:    200 * time.Millisecond,
+			ModifierPoll:     50 * time.Millisecond,
+			ActiveWindowPoll: 500 * time.Millisecond,
+		},
+		List: ListConfig{
+			Insert:    InsertAfterCurrent,
+			Order:     OrderRing,
+			Persist:   true,
+			Workspace: WorkspaceSwitch,
+			Minimized: MinimizedRestore,
+		},
+		Hooks: HooksConfig{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+// DefaultConfigPath returns $XDG_CONFIG_HOME/tr1p-cycle/config.toml.
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine config directory: %v", err)
+	}
+	return filepath.Join(dir, "tr1p-cycle", "config.toml"), nil
+}
+
+// LoadConfig reads path on top of DefaultConfig. A missing file is only an
+// error when mustExist is set, so that running without a config works.
+func LoadConfig(path string, mustExist bool) (Config, error) {
+	cfg := DefaultConfig()
+	// The decoder reuses a slice's backing array, so decoding over the
+	// default excludes would merge them into the file's own.
+	defaults := cfg.Exclude
+	cfg.Exclude = nil
+
+	md, err := toml.DecodeFile(path, &cfg)
+	if errors.Is(err, os.ErrNotExist) && !mustExist {
+		return DefaultConfig(), nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to load config %s: %v", path, err)
+	}
+	if !md.IsDefined("exclude") {
+		cfg.Exclude = defaults
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return Config{}, fmt.Errorf("config %s: unknown keys: %s", path, strings.Join(keys, ", "))
+	}
+
+	if cfg.List.StateFile, err = expandHome(cfg.List.StateFile); err != nil {
+		return Config{}, fmt.Errorf("config %s: list.state_file: %v", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config %s:\n%v", path, err)
+	}
+	return cfg, nil
+}
+
+// expandHome replaces a leading "~/" with the user's home directory, as a
+// shell would.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not expand %s: %v", path, err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// previewMargin is how much narrower and shorter the preview's item list is
+// than the preview window.
+const previewMargin = 100
+
+// Validate reports every invalid value at once rather than stopping at the
+// first.
+func (cfg Config) Validate() error {
+	var errs []error
+
+	type bind struct {
+		name, value string
+		required    bool
+	}
+	binds := []bind{
+		{"keybinds.add", cfg.Keybinds.AddKeybind, true},
+		{"keybinds.remove", cfg.Keybinds.RemoveKeybind, true},
+		{"keybinds.cycle", cfg.Keybinds.CycleKeybind, true},
+		{"keybinds.reverse", cfg.Keybinds.ReverseKeybind, true},
+		{"keybinds.move", cfg.Keybinds.MoveKeybind, false},
+		{"keybinds.move_up", cfg.Keybinds.MoveUpKeybind, false},
+		{"keybinds.move_down", cfg.Keybinds.MoveDownKeybind, false},
+		{"keybinds.switcher", cfg.Keybinds.SwitcherKeybind, false},
+	}
+	for i, b := range cfg.Keybinds.SlotKeybinds() {
+		binds = append(binds, bind{fmt.Sprintf("keybinds.slot_modifiers (slot %d)", i+1), b, true})
+	}
+
+	ringNames := map[string]bool{DefaultRingName: true}
+	for i, r := range cfg.Rings {
+		prefix := fmt.Sprintf("rings[%d]", i)
+		switch {
+		case r.Name == "":
+			errs = append(errs, fmt.Errorf("%s.name must not be empty", prefix))
+		case strings.Contains(r.Name, "/") || r.Name == "." || r.Name == "..":
+			// The name becomes part of the ring's state file name.
+			errs = append(errs, fmt.Errorf("%s.name: %q is not a valid ring name", prefix, r.Name))
+		case ringNames[r.Name]:
+			errs = append(errs, fmt.Errorf("%s.name: ring %q is defined more than once", prefix, r.Name))
+		default:
+			prefix = fmt.Sprintf("rings.%s", r.Name)
+		}
+		ringNames[r.Name] = true
+		if r.SlotModifiers != "" || r.MoveUpKeybind != "" || r.MoveDownKeybind != "" || r.SwitcherKeybind != "" {
+			errs = append(errs, fmt.Errorf("%s: slot_modifiers, move_up, move_down and switcher are only set in [keybinds] and apply to the active ring", prefix))
+		}
+		binds = append(binds,
+			bind{prefix + ".add", r.AddKeybind, false},
+			bind{prefix + ".remove", r.RemoveKeybind, false},
+			bind{prefix + ".cycle", r.CycleKeybind, false},
+			bind{prefix + ".reverse", r.ReverseKeybind, false},
+			bind{prefix + ".move", r.MoveKeybind, false},
+		)
+	}
+
+	seen := make(map[string]string)
+	for _, b := range binds {
+		if b.value == "" {
+			if b.required {
+				errs = append(errs, fmt.Errorf("%s must not be empty", b.name))
+			}
+			continue
+		}
+		kb, err := ParseKeybind(b.value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", b.name, err))
+			continue
+		}
+		key := kb.String()
+		if other, ok := seen[key]; ok {
+			errs = append(errs, fmt.Errorf("%s: %s is already used by %s", b.name, key, other))
+		}
+		seen[key] = b.name
+	}
+
+	for _, section := range []struct {
+		name  string
+		rules []RuleConfig
+	}{{"rules", cfg.Rules}, {"exclude", cfg.Exclude}} {
+		for i, rc := range section.rules {
+			if _, err := compileRule(rc); err != nil {
+				errs = append(errs, fmt.Errorf("%s[%d]: %v", section.name, i, err))
+			}
+			if rc.Ring != "" && !ringNames[rc.Ring] {
+				errs = append(errs, fmt.Errorf("%s[%d].ring: no ring named %q", section.name, i, rc.Ring))
+			}
+		}
+	}
+
+	if cfg.Preview.Width <= previewMargin || cfg.Preview.Height <= previewMargin {
+		errs = append(errs, fmt.Errorf("preview.width and preview.height must be greater than %d", previewMargin))
+	}
+	if cfg.Preview.Layout != LayoutList && cfg.Preview.Layout != LayoutGrid {
+		errs = append(errs, fmt.Errorf("preview.layout must be %q or %q, got %q", LayoutList, LayoutGrid, cfg.Preview.Layout))
+	}
+	if cfg.Preview.ThumbnailWidth <= 0 || cfg.Preview.ThumbnailHeight <= 0 {
+		errs = append(errs, fmt.Errorf("preview.thumbnail_width and preview.thumbnail_height must be positive"))
+	}
+	if cfg.Preview.IconSize <= 0 {
+		errs = append(errs, fmt.Errorf("preview.icon_size must be positive"))
+	}
+	colors := []struct{ name, value string }{
+		{"preview.background", cfg.Preview.Background},
+		{"preview.highlight", cfg.Preview.Highlight},
+		{"preview.highlight_text", cfg.Preview.HighlightText},
+	}
+	for _, c := range colors {
+		if c.value == "" && c.name == "preview.background" {
+			continue
+		}
+		if _, err := parseColor(c.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", c.name, err))
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"timing.cycle_debounce", cfg.Timing.CycleDebounce},
+		{"timing.modifier_poll", cfg.Timing.ModifierPoll},
+		{"timing.active_window_poll", cfg.Timing.ActiveWindowPoll},
+		{"preview.thumbnail_refresh", cfg.Preview.ThumbnailRefresh},
+		{"hooks.timeout", cfg.Hooks.Timeout},
+	}
+	for _, d := range durations {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive duration such as \"200ms\"", d.name))
+		}
+	}
+
+	if cfg.List.Insert != InsertAfterCurrent && cfg.List.Insert != InsertAtEnd {
+		errs = append(errs, fmt.Errorf("list.insert must be %q or %q, got %q", InsertAfterCurrent, InsertAtEnd, cfg.List.Insert))
+	}
+	if cfg.List.Order != OrderRing && cfg.List.Order != OrderMRU {
+		errs = append(errs, fmt.Errorf("list.order must be %q or %q, got %q", OrderRing, OrderMRU, cfg.List.Order))
+	}
+	switch cfg.List.Workspace {
+	case WorkspaceCurrent, WorkspaceSwitch, WorkspaceBring:
+	default:
+		errs = append(errs, fmt.Errorf("list.workspace must be %q, %q or %q, got %q",
+			WorkspaceCurrent, WorkspaceSwitch, WorkspaceBring, cfg.List.Workspace))
+	}
+	if cfg.List.Minimized != MinimizedRestore && cfg.List.Minimized != MinimizedSkip {
+		errs = append(errs, fmt.Errorf("list.minimized must be %q or %q, got %q", MinimizedRestore, MinimizedSkip, cfg.List.Minimized))
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseColor accepts "#rrggbb" and "#rrggbbaa".
+func parseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 && len(hex) != 8 || hex == s {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb or #rrggbbaa", s)
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color %q, expected #rrggbb or #rrggbbaa", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
@@ -0,0 +1,167 @@
+package cycle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExampleConfig(t *testing.T) {
+	cfg, err := LoadConfig(filepath.Join("..", "..", "config.example.toml"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := DefaultConfig(); !reflect.DeepEqual(cfg, want) {
+		t.Errorf("config.example.toml differs from the defaults:\n got %+v\nwant %+v", cfg, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*Config)
+		err  string // substring of the error, empty for none
+	}{
+		{"defaults", func(*Config) {}, ""},
+		{"slot hotkeys", func(cfg *Config) { cfg.Keybinds.SlotModifiers = "Super" }, ""},
+		{"missing cycle", func(cfg *Config) { cfg.Keybinds.CycleKeybind = "" }, "keybinds.cycle must not be empty"},
+		{"bad keybind", func(cfg *Config) { cfg.Keybinds.AddKeybind = "Alt+Nope" }, "keybinds.add"},
+		{"duplicate keybind", func(cfg *Config) { cfg.Keybinds.MoveUpKeybind = "tab+alt" }, "Alt+Tab is already used by keybinds.cycle"},
+		{"slot clash", func(cfg *Config) {
+			cfg.Keybinds.SlotModifiers = "Alt"
+			cfg.Keybinds.MoveKeybind = "Alt+3"
+		}, "already used"},
+		{"unnamed ring", func(cfg *Config) { cfg.Rings = []RingConfig{{}} }, "rings[0].name must not be empty"},
+		{"ring name with a slash", func(cfg *Config) {
+			cfg.Rings = []RingConfig{{Name: "../../evil"}}
+		}, "not a valid ring name"},
+		{"ring name dot dot", func(cfg *Config) { cfg.Rings = []RingConfig{{Name: ".."}} }, "not a valid ring name"},
+		{"duplicate ring", func(cfg *Config) {
+			cfg.Rings = []RingConfig{{Name: DefaultRingName}}
+		}, "defined more than once"},
+		{"slot modifiers on a ring", func(cfg *Config) {
+			cfg.Rings = []RingConfig{{Name: "comms", Keybinds: Keybinds{SlotModifiers: "Super"}}}
+		}, "only set in [keybinds]"},
+		{"rule for unknown ring", func(cfg *Config) { cfg.Rules = []RuleConfig{{Ring: "code", Class: "Alacritty"}} }, `no ring named "code"`},
+		{"preview too small", func(cfg *Config) { cfg.Preview.Height = previewMargin }, "preview.width and preview.height"},
+		{"bad layout", func(cfg *Config) { cfg.Preview.Layout = "table" }, "preview.layout"},
+		{"bad color", func(cfg *Config) { cfg.Preview.Highlight = "green" }, "preview.highlight"},
+		{"zero duration", func(cfg *Config) { cfg.Timing.CycleDebounce = 0 }, "timing.cycle_debounce"},
+		{"negative timeout", func(cfg *Config) { cfg.Hooks.Timeout = -time.Second }, "hooks.timeout"},
+		{"bad insert", func(cfg *Config) { cfg.List.Insert = "start" }, "list.insert"},
+		{"bad order", func(cfg *Config) { cfg.List.Order = "lru" }, "list.order"},
+		{"bad workspace", func(cfg *Config) { cfg.List.Workspace = "all" }, "list.workspace"},
+		{"bad minimized", func(cfg *Config) { cfg.List.Minimized = "hide" }, "list.minimized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := configWith(tt.edit).Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate() = %v, want no error", err)
+			case tt.err != "" && err == nil:
+				t.Errorf("Validate() = nil, want an error containing %q", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Keybinds.AddKeybind = ""
+	cfg.Preview.Layout = "table"
+	cfg.List.Order = "lru"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil")
+	}
+	if n := len(strings.Split(err.Error(), "\n")); n != 3 {
+		t.Errorf("Validate() reported %d errors, want 3:\n%v", n, err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "config.toml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := LoadConfig(write("[list]\nstate_file = \"~/ring.json\"\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(home, "ring.json"); cfg.List.StateFile != want {
+		t.Errorf("state_file = %q, want %q", cfg.List.StateFile, want)
+	}
+
+	if _, err := LoadConfig(write("[list]\nbogus = 1\n"), true); err == nil || !strings.Contains(err.Error(), "list.bogus") {
+		t.Errorf("LoadConfig with an unknown key = %v", err)
+	}
+	if _, err := LoadConfig(write("[preview]\nwidth = 50\n"), true); err == nil {
+		t.Error("LoadConfig with an invalid value succeeded")
+	}
+
+	missing := filepath.Join(dir, "missing.toml")
+	if cfg, err := LoadConfig(missing, false); err != nil || !reflect.DeepEqual(cfg, DefaultConfig()) {
+		t.Errorf("LoadConfig of a missing optional file = %v", err)
+	}
+	if _, err := LoadConfig(missing, true); err == nil {
+		t.Error("LoadConfig of a missing required file succeeded")
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	tests := []struct{ in, want string }{
+		{"~", home},
+		{"~/state/ring.json", filepath.Join(home, "state", "ring.json")},
+		{"/var/ring.json", "/var/ring.json"},
+		{"~other/ring.json", "~other/ring.json"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got, err := expandHome(tt.in); err != nil || got != tt.want {
+			t.Errorf("expandHome(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigExclude(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := "[[exclude]]\nclass = \"Conky\"\n\n[[exclude]]\napp_name = \"polybar\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []RuleConfig{{Class: "Conky"}, {AppName: "polybar"}}
+	if !reflect.DeepEqual(cfg.Exclude, want) {
+		t.Errorf("exclude = %+v, want %+v", cfg.Exclude, want)
+	}
+
+	cl := NewCycleList(DefaultRingName, NewFakeBackend(), cfg)
+	if cl.AddWindow(WindowInfo{ID: 1, Class: "Conky", Title: "conky"}) {
+		t.Error("AddWindow added an excluded window")
+	}
+	if !cl.AddWindow(WindowInfo{ID: 2, Title: "panel", Type: "dock"}) {
+		t.Error("AddWindow skipped a dock, which the file no longer excludes")
+	}
+}
@@ -0,0 +1,162 @@
+package cycle
+
+import (
+	"fmt"
+	"log"
+)
+
+// ItemInfo describes a ring item to clients of the control interfaces.
+// Placeholders and ghosts have a zero Window.
+type ItemInfo struct {
+	Window  WindowID `json:"window"`
+	Title   string   `json:"title"`
+	AppName string   `json:"app_name"`
+	Class   string   `json:"class"`
+	Current bool     `json:"current"`
+}
+
+// Controller carries out the commands that can be sent to a running
+// instance from outside, as opposed to through its hotkeys. Every command
+// takes a ring name; an empty name means the active ring.
+type Controller struct {
+	rings   *Rings
+	preview *Preview
+	quit    func()
+}
+
+// NewController returns a Controller for rings. quit is called by Quit and
+// should stop the application.
+func NewController(rings *Rings, preview *Preview, quit func()) *Controller {
+	return &Controller{rings: rings, preview: preview, quit: quit}
+}
+
+func (c *Controller) ring(name string) (*CycleList, error) {
+	if name == "" {
+		return c.rings.Active(), nil
+	}
+	cl := c.rings.Get(name)
+	if cl == nil {
+		return nil, fmt.Errorf("no ring named %q", name)
+	}
+	return cl, nil
+}
+
+// Add adds the active window to the ring.
+func (c *Controller) Add(ring string) error {
+	cl, err := c.ring(ring)
+	if err != nil {
+		return err
+	}
+	win, err := cl.backend.ActiveWindow()
+	if err != nil {
+		return fmt.Errorf("failed to get active window: %v", err)
+	}
+	if !cl.AddWindow(win) {
+		return fmt.Errorf("%s was not added to ring %s", win.Title, cl.name)
+	}
+	c.refresh()
+	return nil
+}
+
+// Remove removes the active window from the ring.
+func (c *Controller) Remove(ring string) error {
+	cl, err := c.ring(ring)
+	if err != nil {
+		return err
+	}
+	win, err := cl.backend.ActiveWindow()
+	if err != nil {
+		return fmt.Errorf("failed to get active window: %v", err)
+	}
+	if !cl.RemoveWindow(win.ID) {
+		return fmt.Errorf("%s is not in ring %s", win.Title, cl.name)
+	}
+	c.refresh()
+	return nil
+}
+
+// Next focuses the next window in the ring, like a single tap of the cycle
+// hotkey.
+func (c *Controller) Next(ring string) error {
+	return c.step(ring, true)
+}
+
+// Prev focuses the previous window in the ring, like a single tap of the
+// reverse hotkey.
+func (c *Controller) Prev(ring string) error {
+	return c.step(ring, false)
+}
+
+func (c *Controller) step(ring string, forward bool) error {
+	cl, err := c.ring(ring)
+	if err != nil {
+		return err
+	}
+	c.rings.SetActive(cl)
+	if forward {
+		err = cl.FocusNext()
+	} else {
+		err = cl.FocusPrev()
+	}
+	cl.EndCycle()
+	c.refresh()
+	return err
+}
+
+// List returns the ring's items in slot order.
+func (c *Controller) List(ring string) ([]ItemInfo, error) {
+	cl, err := c.ring(ring)
+	if err != nil {
+		return nil, err
+	}
+	items, current := cl.GetItems()
+	infos := make([]ItemInfo, len(items))
+	for i, item := range items {
+		infos[i] = ItemInfo{
+			Window:  item.window,
+			Title:   item.title,
+			AppName: item.appName,
+			Class:   item.class,
+			Current: i == current,
+		}
+	}
+	return infos, nil
+}
+
+// Focus focuses the item at the zero-based index in slot order.
+func (c *Controller) Focus(ring string, index int) error {
+	cl, err := c.ring(ring)
+	if err != nil {
+		return err
+	}
+	items, _ := cl.GetItems()
+	if index < 0 || index >= len(items) {
+		return fmt.Errorf("ring %s has no item %d", cl.name, index)
+	}
+	if items[index].window == 0 {
+		return fmt.Errorf("item %d has no window yet: %s", index, items[index].title)
+	}
+	c.rings.SetActive(cl)
+	if err := cl.FocusSlot(index); err != nil {
+		return err
+	}
+	c.refresh()
+	return nil
+}
+
+// Save writes every ring to its state file.
+func (c *Controller) Save() error {
+	return c.rings.Save()
+}
+
+// Quit stops the application.
+func (c *Controller) Quit() {
+	log.Println("Quit requested")
+	c.quit()
+}
+
+func (c *Controller) refresh() {
+	if c.preview != nil {
+		c.preview.updateContent()
+	}
+}
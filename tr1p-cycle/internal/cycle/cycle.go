@@ -3,62 +3,151 @@ package cycle
 import (
 	"fmt"
 	"log"
-	"os/exec"
-	"strconv"
-	"strings"
+	"os"
+	"regexp"
 	"sync"
-	"time"
 )
 
 type CycleList struct {
-	mu      sync.Mutex
-	head    *CycleItem
-	current *CycleItem
-	track   map[int]*CycleItem
+	mu          sync.Mutex
+	name        string
+	backend     WindowBackend
+	head        *CycleItem
+	current     *CycleItem
+	track       map[WindowID]*CycleItem
+	store       *Store
+	insertAtEnd bool
+	ghosts      bool
+	mru         bool
+	perMonitor  bool
+	workspace   string
+	skipHidden  bool
+	// cycling is set while a cycle hotkey is held in MRU order, so that
+	// walking back through history doesn't reorder it. See EndCycle.
+	cycling bool
+	// lastActive is the most recently active window that isn't one of
+	// ours, whether or not it is in the ring.
+	lastActive WindowID
+	exclude    []*Rule
+	// notify is called with c.mu held for every change worth telling
+	// observers about. It must not call back into the list.
+	notify func(RingEvent)
 }
 
+type RingEventKind int
+
+const (
+	// ItemAdded is sent when a window joins the ring, including when a
+	// placeholder or ghost is matched to a window.
+	ItemAdded RingEventKind = iota
+	// ItemRemoved is sent when a window leaves the ring, including when it
+	// closes and is kept as a ghost.
+	ItemRemoved
+	// CurrentChanged is sent when another item becomes current. The window
+	// is zero once the ring is empty.
+	CurrentChanged
+	// ItemFocused is sent when cycling or a slot focuses a window. Unlike
+	// CurrentChanged it isn't sent when current moves because the current
+	// window was removed.
+	ItemFocused
+)
+
+// RingEvent describes one change to a ring. See Rings.Subscribe.
+type RingEvent struct {
+	Kind    RingEventKind
+	Ring    string
+	Window  WindowID
+	Title   string
+	AppName string
+	PID     int
+}
+
+// CycleItem is one window in the ring. Items restored from disk that haven't
+// been matched to a live window yet have a zero window ID, as do ghosts of
+// closed windows waiting for the application to be relaunched.
 type CycleItem struct {
-	next    *CycleItem
-	prev    *CycleItem
-	title   string
-	process int
-	name    string
-	appName string
+	next         *CycleItem
+	prev         *CycleItem
+	window       WindowID
+	title        string
+	process      int
+	name         string
+	appName      string
+	class        string
+	titlePattern *regexp.Regexp
+	ghost        bool
+	desktop      int // zero-based, or AllDesktops
+	state        WindowState
 }
 
-func NewCycleList() *CycleList {
-	return &CycleList{track: make(map[int]*CycleItem)}
+func NewCycleList(name string, backend WindowBackend, cfg Config) *CycleList {
+	c := &CycleList{
+		name:        name,
+		backend:     backend,
+		track:       make(map[WindowID]*CycleItem),
+		insertAtEnd: cfg.List.Insert == InsertAtEnd,
+		ghosts:      cfg.List.Ghosts,
+		mru:         cfg.List.Order == OrderMRU,
+		perMonitor:  cfg.List.PerMonitor,
+		workspace:   cfg.List.Workspace,
+		skipHidden:  cfg.List.Minimized == MinimizedSkip,
+	}
+
+	rules, errs := compileRules(cfg.Exclude)
+	for _, err := range errs {
+		log.Printf("Ignoring exclude %v\n", err)
+	}
+	for _, r := range rules {
+		if r.appliesTo(name) {
+			c.exclude = append(c.exclude, r)
+		}
+	}
+	return c
 }
 
-func (c *CycleList) Add(title string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *CycleList) Name() string {
+	return c.name
+}
 
-	windowID, windowTitle, processID, err := getActiveWindow()
+func (c *CycleList) Add(title string) {
+	win, err := c.backend.ActiveWindow()
 	if err != nil {
 		log.Printf("Failed to get active window: %v\n", err)
 		return
 	}
+	c.AddWindow(win)
+}
 
-	pid, _ := strconv.Atoi(processID)
-	if _, exists := c.track[pid]; exists {
-		log.Printf("Item already in list: %s\n", windowTitle)
-		return
+// AddWindow adds win to the ring and reports whether it was added.
+func (c *CycleList) AddWindow(win WindowInfo) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.notifyCurrent(c.current)
+
+	if _, exists := c.track[win.ID]; exists {
+		log.Printf("Item already in list: %s\n", win.Title)
+		return false
+	}
+	if c.excluded(win) {
+		log.Printf("Window is excluded from %s: %s\n", c.name, win.Title)
+		return false
 	}
 
-	appName, err := getApplicationName(pid)
-	if err != nil {
-		log.Printf("Failed to get application name: %v\n", err)
-		appName = "Unknown"
+	if c.attachPlaceholders([]WindowInfo{win}, nil) {
+		log.Printf("Added item by attaching placeholder: %s (Window ID: %s)\n", win.Title, win.ID)
+		c.save()
+		return true
 	}
 
-	newItem := &CycleItem{title: windowTitle, process: pid, name: windowTitle, appName: appName}
+	newItem := &CycleItem{window: win.ID, title: win.Title, process: win.PID, name: win.Title, appName: win.AppName, class: win.Class, desktop: win.Desktop, state: win.State}
 
-	if c.head == nil {
+	if c.mru {
+		// The window being added is the one in use, so it goes first.
+		c.appendItem(newItem)
 		c.head = newItem
 		c.current = newItem
-		newItem.next = newItem
-		newItem.prev = newItem
+	} else if c.head == nil || c.insertAtEnd {
+		c.appendItem(newItem)
 	} else {
 		newItem.prev = c.current
 		newItem.next = c.current.next
@@ -66,30 +155,106 @@ func (c *CycleList) Add(title string) {
 		c.current.next = newItem
 	}
 
-	c.track[pid] = newItem
-	log.Printf("Added item: %s (Window ID: %s, App: %s)\n", windowTitle, windowID, appName)
+	c.track[win.ID] = newItem
+	c.emit(ItemAdded, newItem)
+	log.Printf("Added item to %s: %s (Window ID: %s, App: %s)\n", c.name, win.Title, win.ID, win.AppName)
+	c.save()
+	return true
 }
 
-func (c *CycleList) Remove(title string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// excluded reports whether win may not be added, either because an exclude
+// rule matches or because it belongs to us, like the preview window.
+func (c *CycleList) excluded(win WindowInfo) bool {
+	if win.PID == os.Getpid() {
+		return true
+	}
+	for _, r := range c.exclude {
+		if r.Matches(win) {
+			return true
+		}
+	}
+	return false
+}
 
-	_, windowTitle, processID, err := getActiveWindow()
+// emit tells the observer, if any, about item. Callers must hold c.mu.
+func (c *CycleList) emit(kind RingEventKind, item *CycleItem) {
+	if c.notify == nil {
+		return
+	}
+	ev := RingEvent{Kind: kind, Ring: c.name}
+	if item != nil {
+		ev.Window = item.window
+		ev.Title = item.title
+		ev.AppName = item.appName
+		ev.PID = item.process
+	}
+	c.notify(ev)
+}
+
+// notifyCurrent emits CurrentChanged if the current item is no longer prev.
+// Methods that can move current defer it right after locking, so that it
+// runs before the unlock.
+func (c *CycleList) notifyCurrent(prev *CycleItem) {
+	if c.current != prev {
+		c.emit(CurrentChanged, c.current)
+	}
+}
+
+// appendItem inserts item at the end of the ring, just before head.
+func (c *CycleList) appendItem(item *CycleItem) {
+	if c.head == nil {
+		c.head = item
+		c.current = item
+		item.next = item
+		item.prev = item
+		return
+	}
+	item.prev = c.head.prev
+	item.next = c.head
+	c.head.prev.next = item
+	c.head.prev = item
+}
+
+func (c *CycleList) Remove(title string) {
+	win, err := c.backend.ActiveWindow()
 	if err != nil {
 		log.Printf("Failed to get active window: %v\n", err)
 		return
 	}
 
-	pid, _ := strconv.Atoi(processID)
-	if curr, exists := c.track[pid]; exists {
-		c.removeItem(curr)
-		log.Printf("Removed item: %s (Process ID: %d)\n", windowTitle, pid)
-	} else {
-		log.Printf("%v is not in the cycle list, so it won't be removed!\n", windowTitle)
+	if !c.RemoveWindow(win.ID) {
+		log.Printf("%v is not in the cycle list, so it won't be removed!\n", win.Title)
+	}
+}
+
+// RemoveWindow removes the item for id and reports whether there was one.
+func (c *CycleList) RemoveWindow(id WindowID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.notifyCurrent(c.current)
+
+	curr, exists := c.track[id]
+	if !exists {
+		return false
 	}
+	c.removeItem(curr)
+	c.save()
+	log.Printf("Removed item from %s: %s (Window ID: %s)\n", c.name, curr.title, id)
+	return true
+}
+
+// Contains reports whether the window is in the ring.
+func (c *CycleList) Contains(id WindowID) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, exists := c.track[id]
+	return exists
 }
 
 func (c *CycleList) removeItem(item *CycleItem) {
+	if item.window != 0 {
+		c.emit(ItemRemoved, item)
+	}
 	if item.next == item {
 		c.head = nil
 		c.current = nil
@@ -103,85 +268,361 @@ func (c *CycleList) removeItem(item *CycleItem) {
 			c.current = item.next
 		}
 	}
-	delete(c.track, item.process)
+	if item.window != 0 {
+		delete(c.track, item.window)
+	}
 }
 
-func (c *CycleList) FocusNext() {
+func (c *CycleList) FocusNext() error {
+	return c.focusStep(true)
+}
+
+// FocusPrev walks the ring backwards, skipping closed windows the same way
+// FocusNext does.
+func (c *CycleList) FocusPrev() error {
+	return c.focusStep(false)
+}
+
+func (c *CycleList) focusStep(forward bool) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	defer c.notifyCurrent(c.current)
 
 	if c.current == nil {
-		log.Println("No items in the list.")
-		return
+		return fmt.Errorf("ring %s is empty", c.name)
+	}
+
+	windows, err := c.backend.ListWindows()
+	if err != nil {
+		return fmt.Errorf("failed to list windows: %v", err)
+	}
+	open := openSet(windows)
+	c.updateWindows(windows)
+	if c.workspace == WorkspaceCurrent {
+		c.keepCurrentDesktop(open)
+	}
+	if c.skipHidden {
+		c.keepUnminimized(open)
+	}
+	if c.perMonitor {
+		c.keepActiveMonitor(open)
+	}
+
+	if c.mru && !c.cycling {
+		c.startCycle(forward)
 	}
 
 	startItem := c.current
 	for {
-		c.current = c.current.next
-		if c.isWindowOpen(c.current) {
+		if forward {
+			c.current = c.current.next
+		} else {
+			c.current = c.current.prev
+		}
+		if open[c.current.window] {
 			break
 		}
 		if c.current == startItem {
-			log.Println("No open windows in the list.")
+			return fmt.Errorf("ring %s has no open windows", c.name)
+		}
+	}
+
+	if err := c.focusItem(c.current); err != nil {
+		return fmt.Errorf("error focusing window: %v", err)
+	}
+	c.emit(ItemFocused, c.current)
+	log.Printf("Focused on window: %s\n", c.current.title)
+	return nil
+}
+
+// startCycle positions current so that the first step in MRU order lands on
+// the most recently used window other than the active one. Callers must
+// hold c.mu.
+func (c *CycleList) startCycle(forward bool) {
+	c.cycling = true
+	c.current = c.head
+	var activeID WindowID
+	if active, err := c.backend.ActiveWindow(); err == nil {
+		activeID = active.ID
+		// The preview takes focus as it opens, so look past it to the
+		// window it covers.
+		if active.PID == os.Getpid() {
+			activeID = c.lastActive
+		}
+	}
+	if activeID != c.head.window {
+		// Focus is outside the ring, so head itself is the one to go back to.
+		if forward {
+			c.current = c.head.prev
+		} else {
+			c.current = c.head.next
+		}
+	}
+}
+
+// EndCycle is called when the cycle hotkey's modifiers are released. In MRU
+// order the window that was cycled to becomes the most recent one.
+func (c *CycleList) EndCycle() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.cycling {
+		return
+	}
+	c.cycling = false
+	if c.current != nil {
+		c.moveToFront(c.current)
+	}
+}
+
+// moveToFront makes item the head of the ring without changing the order of
+// the others, and saves the new order. Callers must hold c.mu.
+func (c *CycleList) moveToFront(item *CycleItem) {
+	if item == c.head {
+		return
+	}
+	item.prev.next = item.next
+	item.next.prev = item.prev
+	item.prev = c.head.prev
+	item.next = c.head
+	c.head.prev.next = item
+	c.head.prev = item
+	c.head = item
+	c.save()
+}
+
+// MaxSlots is the number of items reachable with slot hotkeys.
+const MaxSlots = 9
+
+// FocusSlot focuses the item at the given zero-based position from head.
+func (c *CycleList) FocusSlot(slot int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.notifyCurrent(c.current)
+
+	item := c.head
+	for i := 0; i < slot && item != nil; i++ {
+		item = item.next
+		if item == c.head {
+			item = nil
+		}
+	}
+	if item == nil {
+		return fmt.Errorf("no item in slot %d", slot+1)
+	}
+	if item.window == 0 {
+		return fmt.Errorf("item in slot %d has no window yet: %s", slot+1, item.title)
+	}
+
+	if err := c.focusItem(item); err != nil {
+		return fmt.Errorf("error focusing window: %v", err)
+	}
+	c.current = item
+	c.emit(ItemFocused, item)
+	log.Printf("Focused on slot %d: %s\n", slot+1, item.title)
+	return nil
+}
+
+// MoveUp moves the current item one slot towards head, wrapping around to
+// the last slot.
+func (c *CycleList) MoveUp() {
+	c.moveCurrentBy(-1)
+}
+
+// MoveDown moves the current item one slot away from head, wrapping around
+// to the first slot.
+func (c *CycleList) MoveDown() {
+	c.moveCurrentBy(1)
+}
+
+// MoveTo moves the current item to the given zero-based slot.
+func (c *CycleList) MoveTo(index int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	items := c.slice()
+	for i, item := range items {
+		if item == c.current {
+			c.moveItem(items, i, index)
 			return
 		}
 	}
+	log.Println("No items in the list.")
+}
 
-	windowID, err := findWindowID(c.current.process)
-	if err != nil {
-		log.Printf("Could not find window for item: %s\n", c.current.title)
+// MoveItem moves the item in slot from to slot to. Out of range targets are
+// clamped to the first or last slot.
+func (c *CycleList) MoveItem(from, to int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	items := c.slice()
+	if from < 0 || from >= len(items) {
+		log.Printf("No item in slot %d\n", from+1)
 		return
 	}
+	c.moveItem(items, from, to)
+}
 
-	err = exec.Command("wmctrl", "-ia", windowID).Run()
-	if err != nil {
-		log.Printf("Error focusing window: %s\n", err)
-	} else {
-		log.Printf("Focused on window: %s\n", c.current.title)
+func (c *CycleList) moveCurrentBy(delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	items := c.slice()
+	for i, item := range items {
+		if item == c.current {
+			n := len(items)
+			c.moveItem(items, i, ((i+delta)%n+n)%n)
+			return
+		}
 	}
+	log.Println("No items in the list.")
 }
 
-func (c *CycleList) isWindowOpen(item *CycleItem) bool {
-	_, err := findWindowID(item.process)
-	return err == nil
+// moveItem relinks the ring so that items[from] ends up at index to.
+// Callers must hold c.mu.
+func (c *CycleList) moveItem(items []*CycleItem, from, to int) {
+	if to < 0 {
+		to = 0
+	}
+	if to >= len(items) {
+		to = len(items) - 1
+	}
+	if from == to {
+		return
+	}
+
+	item := items[from]
+	items = append(items[:from], items[from+1:]...)
+	items = append(items[:to], append([]*CycleItem{item}, items[to:]...)...)
+
+	for i, it := range items {
+		it.next = items[(i+1)%len(items)]
+		it.prev = items[(i-1+len(items))%len(items)]
+	}
+	c.head = items[0]
+	c.save()
+	log.Printf("Moved %s from slot %d to slot %d in %s\n", item.title, from+1, to+1, c.name)
 }
 
-func (c *CycleList) MonitorActiveWindow() {
+// slice returns the items from head in ring order. Callers must hold c.mu.
+func (c *CycleList) slice() []*CycleItem {
+	var items []*CycleItem
+	if c.head == nil {
+		return items
+	}
+	item := c.head
 	for {
-		_, _, processID, err := getActiveWindow()
-		if err != nil {
-			time.Sleep(1 * time.Second)
+		items = append(items, item)
+		item = item.next
+		if item == c.head {
+			return items
+		}
+	}
+}
+
+// updateWindows records the workspace and state of every tracked window,
+// which change without the client list changing. Called with c.mu held.
+func (c *CycleList) updateWindows(windows []WindowInfo) {
+	for _, w := range windows {
+		if item, ok := c.track[w.ID]; ok {
+			item.desktop = w.Desktop
+			item.state = w.State
+		}
+	}
+}
+
+// openSet indexes the windows the window manager currently lists.
+func openSet(windows []WindowInfo) map[WindowID]bool {
+	open := make(map[WindowID]bool, len(windows))
+	for _, w := range windows {
+		open[w.ID] = true
+	}
+	return open
+}
+
+// setActiveWindow makes the item for id current, if the window is in the
+// ring.
+func (c *CycleList) setActiveWindow(id WindowID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.notifyCurrent(c.current)
+	c.lastActive = id
+	if item, exists := c.track[id]; exists {
+		c.current = item
+		if c.mru && !c.cycling {
+			c.moveToFront(item)
+		}
+		log.Printf("Updated current in %s to window: %s (Window ID: %s, Process ID: %d, App: %s)\n", c.name, item.title, item.window, item.process, item.appName)
+	}
+}
+
+// pruneWindows drops items whose windows are not in open. In ghost mode the
+// items stay in the ring, detached, until the application comes back.
+func (c *CycleList) pruneWindows(open map[WindowID]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	defer c.notifyCurrent(c.current)
+	pruned := false
+	for id, item := range c.track {
+		if open[id] {
 			continue
 		}
-		pid, _ := strconv.Atoi(processID)
-		c.mu.Lock()
-		if item, exists := c.track[pid]; exists {
-			c.current = item
-			log.Printf("Updated current to window: %s (Process ID: %d, App: %s)\n", item.title, item.process, item.appName)
+		pruned = true
+		if c.ghosts {
+			c.emit(ItemRemoved, item)
+			delete(c.track, id)
+			item.window = 0
+			item.process = 0
+			item.ghost = true
+			item.desktop = AllDesktops
+			item.state = 0
+			log.Printf("Closed window kept as ghost: %s (Window ID: %s)\n", item.title, id)
+			continue
 		}
-		c.mu.Unlock()
+		c.removeItem(item)
+		log.Printf("Pruned closed window: %s (Window ID: %s)\n", item.title, id)
+	}
+	if pruned {
+		c.save()
+	}
+}
 
-		time.Sleep(500 * time.Millisecond)
+// attachWindows matches placeholders against windows and records the
+// workspace and state of each tracked window. Ghosts skip windows in seen; see
+// attachPlaceholders.
+func (c *CycleList) attachWindows(windows []WindowInfo, seen map[WindowID]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updateWindows(windows)
+	if c.attachPlaceholders(windows, seen) {
+		c.save()
 	}
 }
 
-func (c *CycleList) GetItems() []CycleItem {
+// GetItems returns the items in ring order starting at head, which is also
+// slot order, along with the index of the current item (-1 when empty).
+func (c *CycleList) GetItems() ([]CycleItem, int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	var items []CycleItem
-	if c.current != nil {
-		item := c.current
+	current := -1
+	if c.head != nil {
+		item := c.head
 		for {
+			if item == c.current {
+				current = len(items)
+			}
 			items = append(items, *item)
 			item = item.next
-			if item == c.current {
+			if item == c.head {
 				break
 			}
 		}
 	}
 
-	return items
+	return items, current
 }
 
 func (c *CycleList) GetCurrentItem() *CycleItem {
@@ -202,74 +643,10 @@ func (c *CycleList) PrintItems() {
 	fmt.Println("Current items in the cycle list:")
 	current := c.head
 	for {
-		fmt.Printf("Item: %s (Process ID: %d, App: %s)\n", current.name, current.process, current.appName)
+		fmt.Printf("Item: %s (Window ID: %s, Process ID: %d, App: %s)\n", current.name, current.window, current.process, current.appName)
 		current = current.next
 		if current == c.head {
 			break
 		}
 	}
 }
-
-func findProcessID(processName string) (int, error) {
-	out, err := exec.Command("pgrep", "-f", processName).Output()
-	if err != nil {
-		return 0, fmt.Errorf("error running pgrep command: %v", err)
-	}
-
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
-	if len(lines) > 0 {
-		return strconv.Atoi(lines[0])
-	}
-
-	return 0, fmt.Errorf("no process found for name: %s", processName)
-}
-
-func findWindowID(processID int) (string, error) {
-	out, err := exec.Command("wmctrl", "-lp").Output()
-	if err != nil {
-		return "", fmt.Errorf("error running wmctrl command: %v", err)
-	}
-
-	lines := strings.Split(string(out), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, fmt.Sprintf(" %d ", processID)) {
-			fields := strings.Fields(line)
-			if len(fields) > 0 {
-				return fields[0], nil
-			}
-		}
-	}
-
-	return "", fmt.Errorf("no window found for process ID: %d", processID)
-}
-
-func getActiveWindow() (string, string, string, error) {
-	idBytes, err := exec.Command("xdotool", "getactivewindow").Output()
-	if err != nil {
-		return "", "", "", fmt.Errorf("could not get active window ID: %v", err)
-	}
-	windowID := strings.TrimSpace(string(idBytes))
-
-	titleBytes, err := exec.Command("xdotool", "getwindowname", windowID).Output()
-	if err != nil {
-		return "", "", "", fmt.Errorf("could not get window title: %v", err)
-	}
-	windowTitle := strings.TrimSpace(string(titleBytes))
-
-	pidBytes, err := exec.Command("xdotool", "getwindowpid", windowID).Output()
-	if err != nil {
-		return "", "", "", fmt.Errorf("could not get PID for window: %v", err)
-	}
-	pid := strings.TrimSpace(string(pidBytes))
-
-	return windowID, windowTitle, pid, nil
-}
-
-func getApplicationName(pid int) (string, error) {
-	cmd := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("error getting application name: %v", err)
-	}
-	return strings.TrimSpace(string(output)), nil
-}
@@ -0,0 +1,332 @@
+package cycle
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestList returns a list over a FakeBackend with one window per title,
+// numbered from 1 and added in order. The first window ends up active and
+// current.
+func newTestList(t *testing.T, cfg Config, titles ...string) (*FakeBackend, *CycleList) {
+	t.Helper()
+	backend := NewFakeBackend()
+	for i, title := range titles {
+		backend.Open(WindowInfo{ID: WindowID(i + 1), Title: title})
+	}
+	cl := NewCycleList(DefaultRingName, backend, cfg)
+	for i := range titles {
+		backend.Focus(WindowID(i + 1))
+		cl.Add("")
+	}
+	if len(titles) > 0 {
+		backend.Focus(1)
+		cl.setActiveWindow(1)
+	}
+	return backend, cl
+}
+
+// ringString renders the ring in slot order with the current item in
+// brackets, e.g. "a [b] c".
+func ringString(cl *CycleList) string {
+	items, current := cl.GetItems()
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = item.title
+		if i == current {
+			parts[i] = "[" + item.title + "]"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func configWith(edit func(*Config)) Config {
+	cfg := DefaultConfig()
+	edit(&cfg)
+	return cfg
+}
+
+var insertAtEnd = configWith(func(cfg *Config) { cfg.List.Insert = InsertAtEnd })
+
+func activeTitle(t *testing.T, b *FakeBackend) string {
+	t.Helper()
+	win, err := b.ActiveWindow()
+	if err != nil {
+		return ""
+	}
+	return win.Title
+}
+
+func TestAddWindow(t *testing.T) {
+	tests := []struct {
+		insert string
+		want   string
+	}{
+		{InsertAfterCurrent, "[a] c b"},
+		{InsertAtEnd, "[a] b c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.insert, func(t *testing.T) {
+			cl := NewCycleList(DefaultRingName, NewFakeBackend(), configWith(func(cfg *Config) {
+				cfg.List.Insert = tt.insert
+			}))
+			for i, title := range []string{"a", "b", "c"} {
+				if !cl.AddWindow(WindowInfo{ID: WindowID(i + 1), Title: title}) {
+					t.Fatalf("AddWindow(%s) = false", title)
+				}
+			}
+			if got := ringString(cl); got != tt.want {
+				t.Errorf("ring = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddWindowRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		win  WindowInfo
+	}{
+		{"duplicate", WindowInfo{ID: 1, Title: "a"}},
+		{"own window", WindowInfo{ID: 2, Title: "preview", PID: os.Getpid()}},
+		{"excluded type", WindowInfo{ID: 3, Title: "panel", Type: "dock"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, cl := newTestList(t, DefaultConfig(), "a")
+			if cl.AddWindow(tt.win) {
+				t.Errorf("AddWindow(%+v) = true, want false", tt.win)
+			}
+			if got := ringString(cl); got != "[a]" {
+				t.Errorf("ring = %q, want %q", got, "[a]")
+			}
+		})
+	}
+}
+
+func TestRemoveWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		remove WindowID
+		ok     bool
+		want   string
+	}{
+		{"current", 1, true, "[b] c"},
+		{"other", 2, true, "[a] c"},
+		{"missing", 9, false, "[a] b c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, cl := newTestList(t, insertAtEnd, "a", "b", "c")
+			if ok := cl.RemoveWindow(tt.remove); ok != tt.ok {
+				t.Fatalf("RemoveWindow(%d) = %v, want %v", tt.remove, ok, tt.ok)
+			}
+			if got := ringString(cl); got != tt.want {
+				t.Errorf("ring = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, cl := newTestList(t, insertAtEnd, "a")
+	cl.RemoveWindow(1)
+	if items, current := cl.GetItems(); len(items) != 0 || current != -1 {
+		t.Errorf("GetItems() after removing the last item = %d items, current %d", len(items), current)
+	}
+}
+
+func TestFocusStep(t *testing.T) {
+	tests := []struct {
+		name    string
+		closed  []WindowID
+		forward []bool
+		want    string
+	}{
+		{"next", nil, []bool{true}, "a [b] c"},
+		{"prev wraps", nil, []bool{false}, "a b [c]"},
+		{"next wraps", nil, []bool{true, true, true}, "[a] b c"},
+		{"skips closed", []WindowID{2}, []bool{true}, "a b [c]"},
+		{"all others closed", []WindowID{2, 3}, []bool{true}, "[a] b c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, cl := newTestList(t, insertAtEnd, "a", "b", "c")
+			for _, id := range tt.closed {
+				backend.Close(id)
+			}
+			for _, forward := range tt.forward {
+				if forward {
+					cl.FocusNext()
+				} else {
+					cl.FocusPrev()
+				}
+			}
+			if got := ringString(cl); got != tt.want {
+				t.Errorf("ring = %q, want %q", got, tt.want)
+			}
+			items, current := cl.GetItems()
+			if got := activeTitle(t, backend); got != items[current].title {
+				t.Errorf("active window = %q, want the current item %q", got, items[current].title)
+			}
+		})
+	}
+}
+
+func TestFocusSlot(t *testing.T) {
+	backend, cl := newTestList(t, insertAtEnd, "a", "b", "c")
+	if err := cl.FocusSlot(2); err != nil {
+		t.Fatalf("FocusSlot(2): %v", err)
+	}
+	if got := ringString(cl); got != "a b [c]" || activeTitle(t, backend) != "c" {
+		t.Errorf("ring = %q, active %q; want c focused", got, activeTitle(t, backend))
+	}
+	if err := cl.FocusSlot(3); err == nil {
+		t.Error("FocusSlot(3) succeeded on a ring of three")
+	}
+}
+
+func TestMRU(t *testing.T) {
+	mru := configWith(func(cfg *Config) { cfg.List.Order = OrderMRU })
+	tests := []struct {
+		name   string
+		cycles []int // number of steps per cycle, each followed by EndCycle
+		want   string
+	}{
+		{"added windows go first", nil, "[a] c b"},
+		{"one step goes back", []int{1}, "[c] a b"},
+		{"two quick cycles return", []int{1, 1}, "[a] c b"},
+		{"holding walks further", []int{2}, "[b] a c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, cl := newTestList(t, mru, "a", "b", "c")
+			for _, steps := range tt.cycles {
+				for i := 0; i < steps; i++ {
+					cl.FocusNext()
+				}
+				cl.EndCycle()
+			}
+			if got := ringString(cl); got != tt.want {
+				t.Errorf("ring = %q, want %q", got, tt.want)
+			}
+			items, _ := cl.GetItems()
+			if got := activeTitle(t, backend); got != items[0].title {
+				t.Errorf("active window = %q, want the first item %q", got, items[0].title)
+			}
+		})
+	}
+}
+
+func TestMoveItem(t *testing.T) {
+	tests := []struct {
+		from, to int
+		want     string
+	}{
+		{0, 2, "b c [a] d"},
+		{3, 0, "d [a] b c"},
+		{1, 9, "[a] c d b"},
+		{2, -5, "c [a] b d"},
+		{1, 1, "[a] b c d"},
+		{7, 0, "[a] b c d"},
+	}
+	for _, tt := range tests {
+		_, cl := newTestList(t, insertAtEnd, "a", "b", "c", "d")
+		cl.MoveItem(tt.from, tt.to)
+		if got := ringString(cl); got != tt.want {
+			t.Errorf("MoveItem(%d, %d): ring = %q, want %q", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestMoveCurrent(t *testing.T) {
+	tests := []struct {
+		name string
+		move func(*CycleList)
+		want string
+	}{
+		{"up wraps", (*CycleList).MoveUp, "b c [a]"},
+		{"down", (*CycleList).MoveDown, "b [a] c"},
+		{"to", func(cl *CycleList) { cl.MoveTo(2) }, "b c [a]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, cl := newTestList(t, insertAtEnd, "a", "b", "c")
+			tt.move(cl)
+			if got := ringString(cl); got != tt.want {
+				t.Errorf("ring = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// focusFailer is a backend whose windows can never be focused.
+type focusFailer struct {
+	*FakeBackend
+}
+
+func (focusFailer) Focus(id WindowID) error {
+	return fmt.Errorf("window %s refused focus", id)
+}
+
+func TestFocusStepErrors(t *testing.T) {
+	empty := NewCycleList(DefaultRingName, NewFakeBackend(), insertAtEnd)
+	if err := empty.FocusNext(); err == nil {
+		t.Error("FocusNext() on an empty ring succeeded")
+	}
+
+	backend, cl := newTestList(t, insertAtEnd, "a", "b", "c")
+	for _, id := range []WindowID{1, 2, 3} {
+		backend.Close(id)
+	}
+	if err := cl.FocusPrev(); err == nil {
+		t.Error("FocusPrev() with no open windows succeeded")
+	}
+
+	_, cl = newTestList(t, insertAtEnd, "a", "b")
+	cl.backend = focusFailer{cl.backend.(*FakeBackend)}
+	if err := cl.FocusNext(); err == nil || !strings.Contains(err.Error(), "refused focus") {
+		t.Errorf("FocusNext() = %v, want the backend's error", err)
+	}
+}
+
+func TestMRUPastPreview(t *testing.T) {
+	mru := configWith(func(cfg *Config) { cfg.List.Order = OrderMRU })
+	backend, cl := newTestList(t, mru, "a", "b", "c")
+	// The preview opens over a and takes focus before the first step.
+	backend.Open(WindowInfo{ID: 9, Title: "preview", PID: os.Getpid()})
+	backend.Focus(9)
+
+	cl.FocusNext()
+	cl.EndCycle()
+	if got := ringString(cl); got != "[c] a b" {
+		t.Errorf("ring = %q, want %q", got, "[c] a b")
+	}
+}
+
+func TestMRUSaved(t *testing.T) {
+	mru := configWith(func(cfg *Config) { cfg.List.Order = OrderMRU })
+	backend, cl := newTestList(t, mru, "a", "b", "c")
+	store := NewStore(filepath.Join(t.TempDir(), "ring.json"))
+	cl.store = store
+
+	cl.FocusNext()
+	cl.EndCycle()
+	backend.Focus(2)
+	cl.setActiveWindow(2)
+
+	cl.flush()
+	saved, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range saved {
+		got = append(got, s.Title)
+	}
+	if want := []string{"b", "c", "a"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("saved order = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+package cycle
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// iconThemeSizes is the order hicolor sizes are tried in. Icons are shown
+// small, so sizes close to that come first.
+var iconThemeSizes = []string{"48x48", "32x32", "64x64", "24x24", "128x128", "256x256"}
+
+// desktopIconPath finds the icon of the first application whose desktop
+// entry is named after one of names, such as the process name or WM_CLASS.
+// Only PNG and SVG icons are returned; it returns "" when none is found.
+func desktopIconPath(names ...string) string {
+	dirs := dataDirs()
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		for _, n := range []string{name, strings.ToLower(name)} {
+			for _, dir := range dirs {
+				icon := desktopEntryIcon(filepath.Join(dir, "applications", n+".desktop"))
+				if icon == "" {
+					continue
+				}
+				if path := findIcon(dirs, icon); path != "" {
+					return path
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// desktopEntryIcon returns the Icon key of a desktop entry file.
+func desktopEntryIcon(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	inEntry := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inEntry = line == "[Desktop Entry]"
+			continue
+		}
+		if inEntry && strings.HasPrefix(line, "Icon=") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Icon="))
+		}
+	}
+	return ""
+}
+
+// findIcon resolves an icon name from a desktop entry to a PNG or SVG file
+// in the hicolor theme or pixmaps, preferring PNGs of a fitting size.
+// Absolute paths are used as they are.
+func findIcon(dirs []string, icon string) string {
+	if filepath.IsAbs(icon) {
+		if (strings.HasSuffix(icon, ".png") || strings.HasSuffix(icon, ".svg")) && fileExists(icon) {
+			return icon
+		}
+		return ""
+	}
+
+	icon = strings.TrimSuffix(strings.TrimSuffix(icon, ".png"), ".svg")
+	var candidates []string
+	for _, dir := range dirs {
+		for _, size := range iconThemeSizes {
+			candidates = append(candidates, filepath.Join(dir, "icons", "hicolor", size, "apps", icon+".png"))
+		}
+		candidates = append(candidates, filepath.Join(dir, "icons", "hicolor", "scalable", "apps", icon+".svg"))
+	}
+	for _, dir := range dirs {
+		candidates = append(candidates,
+			filepath.Join(dir, "pixmaps", icon+".png"),
+			filepath.Join(dir, "pixmaps", icon+".svg"))
+	}
+	for _, path := range candidates {
+		if fileExists(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// dataDirs returns $XDG_DATA_HOME followed by $XDG_DATA_DIRS, with the
+// defaults from the XDG base directory spec.
+func dataDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		dirs = append(dirs, dir)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".local", "share"))
+	}
+
+	system := os.Getenv("XDG_DATA_DIRS")
+	if system == "" {
+		system = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(system) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,53 @@
+package cycle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindIcon(t *testing.T) {
+	user, system := t.TempDir(), t.TempDir()
+	files := []string{
+		"icons/hicolor/scalable/apps/editor.svg",
+		"icons/hicolor/48x48/apps/term.png",
+		"icons/hicolor/scalable/apps/term.svg",
+		"pixmaps/legacy.png",
+		"pixmaps/vector.svg",
+		"pixmaps/photo.xpm",
+	}
+	for _, f := range files {
+		path := filepath.Join(system, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dirs := []string{user, system}
+
+	tests := []struct {
+		icon string
+		want string
+	}{
+		{"editor", "icons/hicolor/scalable/apps/editor.svg"},
+		{"term", "icons/hicolor/48x48/apps/term.png"},
+		{"term.svg", "icons/hicolor/48x48/apps/term.png"},
+		{"legacy", "pixmaps/legacy.png"},
+		{"vector", "pixmaps/vector.svg"},
+		{"photo", ""},
+		{"missing", ""},
+		{filepath.Join(system, "pixmaps/vector.svg"), "pixmaps/vector.svg"},
+		{filepath.Join(system, "pixmaps/photo.xpm"), ""},
+	}
+	for _, tt := range tests {
+		want := ""
+		if tt.want != "" {
+			want = filepath.Join(system, tt.want)
+		}
+		if got := findIcon(dirs, tt.icon); got != want {
+			t.Errorf("findIcon(%q) = %q, want %q", tt.icon, got, want)
+		}
+	}
+}
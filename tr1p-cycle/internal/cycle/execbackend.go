@@ -0,0 +1,210 @@
+package cycle
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ExecBackend talks to the window manager by shelling out to xdotool,
+// wmctrl and ps.
+type ExecBackend struct {
+	mu    sync.Mutex
+	names map[int]string
+}
+
+func NewExecBackend() *ExecBackend {
+	return &ExecBackend{names: make(map[int]string)}
+}
+
+func (b *ExecBackend) ActiveWindow() (WindowInfo, error) {
+	idBytes, err := exec.Command("xdotool", "getactivewindow").Output()
+	if err != nil {
+		return WindowInfo{}, fmt.Errorf("could not get active window ID: %v", err)
+	}
+	id, err := parseWindowID(strings.TrimSpace(string(idBytes)))
+	if err != nil {
+		return WindowInfo{}, err
+	}
+	return b.WindowInfo(id)
+}
+
+func (b *ExecBackend) WindowInfo(id WindowID) (WindowInfo, error) {
+	windows, err := b.ListWindows()
+	if err != nil {
+		return WindowInfo{}, err
+	}
+	for _, w := range windows {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return WindowInfo{}, fmt.Errorf("no window found for ID: %s", id)
+}
+
+func (b *ExecBackend) ListWindows() ([]WindowInfo, error) {
+	out, err := exec.Command("wmctrl", "-lpx").Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running wmctrl command: %v", err)
+	}
+
+	var windows []WindowInfo
+	for _, line := range strings.Split(string(out), "\n") {
+		w, ok := parseWmctrlLine(line)
+		if !ok {
+			continue
+		}
+		w.AppName = b.applicationName(w.PID)
+		windows = append(windows, w)
+	}
+	b.forgetNames(windows)
+
+	return windows, nil
+}
+
+// parseWmctrlLine parses one line of "wmctrl -lpx" output:
+//
+//	<id> <desktop> <pid> <instance.class> <host> <title...>
+//
+// The title is taken as it is, since wmctrl separates it from the host by a
+// single space and titles may contain runs of spaces themselves.
+func parseWmctrlLine(line string) (WindowInfo, bool) {
+	fields, title := cutFields(line, 5)
+	if len(fields) < 5 {
+		return WindowInfo{}, false
+	}
+	id, err := parseWindowID(fields[0])
+	if err != nil {
+		return WindowInfo{}, false
+	}
+	desktop, err := strconv.Atoi(fields[1])
+	if err != nil || desktop < 0 {
+		desktop = AllDesktops
+	}
+	pid, _ := strconv.Atoi(fields[2])
+	class := fields[3]
+	if i := strings.LastIndex(class, "."); i >= 0 {
+		class = class[i+1:]
+	}
+	return WindowInfo{
+		ID:      id,
+		Title:   title,
+		PID:     pid,
+		Class:   class,
+		Desktop: desktop,
+	}, true
+}
+
+// cutFields splits the first n space-separated fields off s and returns
+// them with the rest of s, less the one space in front of it.
+func cutFields(s string, n int) ([]string, string) {
+	var fields []string
+	for len(fields) < n {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return fields, ""
+		}
+		end := strings.IndexByte(s, ' ')
+		if end < 0 {
+			return append(fields, s), ""
+		}
+		fields = append(fields, s[:end])
+		s = s[end:]
+	}
+	return fields, strings.TrimPrefix(s, " ")
+}
+
+func (b *ExecBackend) Focus(id WindowID) error {
+	if err := exec.Command("wmctrl", "-ia", id.String()).Run(); err != nil {
+		return fmt.Errorf("error focusing window: %v", err)
+	}
+	return nil
+}
+
+func (b *ExecBackend) CurrentDesktop() (int, error) {
+	out, err := exec.Command("wmctrl", "-d").Output()
+	if err != nil {
+		return 0, fmt.Errorf("error running wmctrl command: %v", err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		// <desktop> <* for the current one, - otherwise> ...
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == "*" {
+			return strconv.Atoi(fields[0])
+		}
+	}
+	return 0, fmt.Errorf("no current desktop in wmctrl output")
+}
+
+func (b *ExecBackend) SwitchDesktop(desktop int) error {
+	if err := exec.Command("wmctrl", "-s", strconv.Itoa(desktop)).Run(); err != nil {
+		return fmt.Errorf("error switching desktop: %v", err)
+	}
+	return nil
+}
+
+func (b *ExecBackend) MoveToDesktop(id WindowID, desktop int) error {
+	if err := exec.Command("wmctrl", "-ir", id.String(), "-t", strconv.Itoa(desktop)).Run(); err != nil {
+		return fmt.Errorf("error moving window to desktop: %v", err)
+	}
+	return nil
+}
+
+// applicationName caches lookups so that listing windows doesn't spawn ps
+// for every window on every call.
+func (b *ExecBackend) applicationName(pid int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if name, ok := b.names[pid]; ok {
+		return name
+	}
+	name, err := getApplicationName(pid)
+	if err != nil {
+		return "Unknown"
+	}
+	b.names[pid] = name
+	return name
+}
+
+// forgetNames drops the names of processes that no longer own a window, so
+// that a reused PID is looked up again.
+func (b *ExecBackend) forgetNames(windows []WindowInfo) {
+	live := make(map[int]bool, len(windows))
+	for _, w := range windows {
+		live[w.PID] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for pid := range b.names {
+		if !live[pid] {
+			delete(b.names, pid)
+		}
+	}
+}
+
+func getApplicationName(pid int) (string, error) {
+	cmd := exec.Command("ps", "-p", strconv.Itoa(pid), "-o", "comm=")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error getting application name: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func findProcessID(processName string) (int, error) {
+	out, err := exec.Command("pgrep", "-f", processName).Output()
+	if err != nil {
+		return 0, fmt.Errorf("error running pgrep command: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) > 0 {
+		return strconv.Atoi(lines[0])
+	}
+
+	return 0, fmt.Errorf("no process found for name: %s", processName)
+}
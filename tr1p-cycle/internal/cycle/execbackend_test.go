@@ -0,0 +1,39 @@
+package cycle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWmctrlLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want WindowInfo
+		ok   bool
+	}{
+		{
+			"0x03a00007  0 4242   alacritty.Alacritty   host ~/src  (main)",
+			WindowInfo{ID: 0x03a00007, Desktop: 0, PID: 4242, Class: "Alacritty", Title: "~/src  (main)"},
+			true,
+		},
+		{
+			"0x01e00003 -1 901    Navigator.firefox     host  leading space",
+			WindowInfo{ID: 0x01e00003, Desktop: AllDesktops, PID: 901, Class: "firefox", Title: " leading space"},
+			true,
+		},
+		{
+			"0x02000001  2 77     conky.Conky           host",
+			WindowInfo{ID: 0x02000001, Desktop: 2, PID: 77, Class: "Conky"},
+			true,
+		},
+		{"", WindowInfo{}, false},
+		{"0x02000001  2 77", WindowInfo{}, false},
+		{"window  2 77 conky.Conky host title", WindowInfo{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseWmctrlLine(tt.line)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWmctrlLine(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package cycle
+
+import (
+	"fmt"
+	"sync"
+)
+
+// FakeBackend is an in-memory WindowBackend for exercising CycleList
+// without an X server.
+type FakeBackend struct {
+	mu      sync.Mutex
+	windows []WindowInfo
+	active  WindowID
+}
+
+func NewFakeBackend(windows ...WindowInfo) *FakeBackend {
+	b := &FakeBackend{windows: windows}
+	if len(windows) > 0 {
+		b.active = windows[0].ID
+	}
+	return b
+}
+
+// Open adds a window, replacing any existing window with the same ID.
+func (b *FakeBackend) Open(w WindowInfo) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.windows {
+		if b.windows[i].ID == w.ID {
+			b.windows[i] = w
+			return
+		}
+	}
+	b.windows = append(b.windows, w)
+}
+
+// Close removes a window. Closing the active window leaves nothing active.
+func (b *FakeBackend) Close(id WindowID) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.windows {
+		if b.windows[i].ID == id {
+			b.windows = append(b.windows[:i], b.windows[i+1:]...)
+			break
+		}
+	}
+	if b.active == id {
+		b.active = 0
+	}
+}
+
+func (b *FakeBackend) ActiveWindow() (WindowInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.active == 0 {
+		return WindowInfo{}, fmt.Errorf("no active window")
+	}
+	return b.find(b.active)
+}
+
+func (b *FakeBackend) ListWindows() ([]WindowInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	windows := make([]WindowInfo, len(b.windows))
+	copy(windows, b.windows)
+	return windows, nil
+}
+
+func (b *FakeBackend) Focus(id WindowID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.find(id); err != nil {
+		return err
+	}
+	b.active = id
+	return nil
+}
+
+func (b *FakeBackend) WindowInfo(id WindowID) (WindowInfo, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.find(id)
+}
+
+func (b *FakeBackend) find(id WindowID) (WindowInfo, error) {
+	for _, w := range b.windows {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return WindowInfo{}, fmt.Errorf("no window with ID %s", id)
+}
@@ -0,0 +1,70 @@
+package cycle
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// fuzzyScore reports whether every rune of query appears in text in order,
+// ignoring case, and how good the match is. Runs of consecutive matches and
+// matches at the start of a word score higher, so "fx" ranks "Firefox"
+// below "fx-terminal" and "term" ranks "terminal" above "tiled emacs rm".
+func fuzzyScore(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(text))
+	if len(q) == 0 {
+		return 0, true
+	}
+
+	score, qi, run := 0, 0, 0
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			run = 0
+			continue
+		}
+		score++
+		run++
+		score += run - 1
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 3
+		}
+		qi++
+	}
+	if qi < len(q) {
+		return 0, false
+	}
+	// Prefer shorter texts when the matches are equally good.
+	return score*100 - len(t), true
+}
+
+// rankWindows returns the windows matching query by title or application
+// name, best first. An empty query keeps every window in its given order.
+func rankWindows(windows []WindowInfo, query string) []WindowInfo {
+	type ranked struct {
+		win   WindowInfo
+		score int
+	}
+	var matches []ranked
+	for _, w := range windows {
+		title, okTitle := fuzzyScore(query, w.Title)
+		app, okApp := fuzzyScore(query, w.AppName)
+		switch {
+		case okTitle && okApp:
+			matches = append(matches, ranked{win: w, score: max(title, app)})
+		case okTitle:
+			matches = append(matches, ranked{win: w, score: title})
+		case okApp:
+			matches = append(matches, ranked{win: w, score: app})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	result := make([]WindowInfo, len(matches))
+	for i, m := range matches {
+		result[i] = m.win
+	}
+	return result
+}
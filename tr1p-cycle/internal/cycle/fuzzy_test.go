@@ -0,0 +1,68 @@
+package cycle
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, text string
+		ok          bool
+	}{
+		{"", "anything", true},
+		{"ff", "Firefox", true},
+		{"FX", "firefox", true},
+		{"xf", "firefox", false},
+		{"term", "term", true},
+		{"terms", "term", false},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.query, tt.text); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) matched = %v, want %v", tt.query, tt.text, ok, tt.ok)
+		}
+	}
+
+	// Each pair is query, better text, worse text.
+	better := [][3]string{
+		{"term", "terminal", "tiled emacs rm"},
+		{"fx", "fx-terminal", "Firefox"},
+		{"code", "code", "code - project"},
+		{"ed", "text editor", "firefox sidebar"},
+	}
+	for _, b := range better {
+		hi, _ := fuzzyScore(b[0], b[1])
+		lo, _ := fuzzyScore(b[0], b[2])
+		if hi <= lo {
+			t.Errorf("fuzzyScore(%q): %q scored %d, not above %q at %d", b[0], b[1], hi, b[2], lo)
+		}
+	}
+}
+
+func TestRankWindows(t *testing.T) {
+	windows := []WindowInfo{
+		{ID: 1, Title: "Mozilla Firefox", AppName: "firefox"},
+		{ID: 2, Title: "fx-terminal", AppName: "xterm"},
+		{ID: 3, Title: "tiled emacs rm", AppName: "emacs"},
+		{ID: 4, Title: "terminal", AppName: "alacritty"},
+	}
+	tests := []struct {
+		query string
+		want  []WindowID
+	}{
+		{"", []WindowID{1, 2, 3, 4}},
+		{"fx", []WindowID{2, 1}},
+		{"term", []WindowID{4, 2, 3}},
+		{"alac", []WindowID{4}},
+		{"zzz", []WindowID{}},
+	}
+	for _, tt := range tests {
+		got := []WindowID{}
+		for _, w := range rankWindows(windows, tt.query) {
+			got = append(got, w.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("rankWindows(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
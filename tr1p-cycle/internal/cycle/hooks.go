@@ -0,0 +1,102 @@
+package cycle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// hookEvent is what a hook receives as JSON on stdin. The same values are
+// also set as TR1P_* environment variables.
+type hookEvent struct {
+	Event   string `json:"event"`
+	Ring    string `json:"ring"`
+	Window  string `json:"window"`
+	Title   string `json:"title"`
+	AppName string `json:"app_name"`
+	PID     int    `json:"pid"`
+}
+
+type hookRunner struct {
+	cfg    HooksConfig
+	events <-chan RingEvent
+}
+
+// StartHooks runs the configured hooks for changes to rings. Hooks run one
+// at a time, in order, on a goroutine of their own so they never hold up
+// the hotkeys.
+func StartHooks(rings *Rings, cfg HooksConfig) {
+	if cfg.OnAdd == "" && cfg.OnRemove == "" && cfg.OnFocus == "" {
+		return
+	}
+
+	h := &hookRunner{cfg: cfg, events: rings.SubscribeQueue("Hooks", nil)}
+	go h.run()
+}
+
+func (h *hookRunner) run() {
+	for ev := range h.events {
+		var name, command string
+		switch ev.Kind {
+		case ItemAdded:
+			name, command = "add", h.cfg.OnAdd
+		case ItemRemoved:
+			name, command = "remove", h.cfg.OnRemove
+		case ItemFocused:
+			name, command = "focus", h.cfg.OnFocus
+		}
+		if command == "" || ev.Window == 0 {
+			continue
+		}
+		h.runHook(command, hookEvent{
+			Event:   name,
+			Ring:    ev.Ring,
+			Window:  ev.Window.String(),
+			Title:   ev.Title,
+			AppName: ev.AppName,
+			PID:     ev.PID,
+		})
+	}
+}
+
+func (h *hookRunner) runHook(command string, ev hookEvent) {
+	input, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("Failed to encode hook event: %v\n", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.cfg.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Env = append(os.Environ(),
+		"TR1P_EVENT="+ev.Event,
+		"TR1P_RING="+ev.Ring,
+		"TR1P_WINDOW="+ev.Window,
+		"TR1P_TITLE="+ev.Title,
+		"TR1P_APP_NAME="+ev.AppName,
+		"TR1P_PID="+strconv.Itoa(ev.PID),
+	)
+	// Run the hook in its own process group so a timeout also kills
+	// anything it started.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Printf("Hook for %s timed out after %v: %s\n", ev.Event, h.cfg.Timeout, command)
+	} else if err != nil {
+		log.Printf("Hook for %s failed: %v: %s\n", ev.Event, err, bytes.TrimSpace(out))
+	}
+}
@@ -0,0 +1,171 @@
+package cycle
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"image/png"
+	"log"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+// iconCache holds the icon of every window shown in the preview. Windows
+// without a usable _NET_WM_ICON, and placeholders, fall back to the icon of
+// the application's desktop entry, which is cached by application. Icons are
+// looked up in the background, like thumbnails, and onUpdate runs once the
+// lookups queued by Get have found something.
+type iconCache struct {
+	mu       sync.Mutex
+	source   WindowIconSource
+	size     int
+	windows  map[WindowID]fyne.Resource
+	apps     map[string]fyne.Resource
+	queue    []iconRequest
+	queued   map[iconRequest]bool
+	running  bool
+	onUpdate func()
+}
+
+type iconRequest struct {
+	window  WindowID
+	appName string
+	class   string
+}
+
+// newIconCache returns a cache reading window icons from source, which may
+// be nil if the backend can't provide them.
+func newIconCache(source WindowIconSource, size int, onUpdate func()) *iconCache {
+	return &iconCache{
+		source:   source,
+		size:     size,
+		windows:  make(map[WindowID]fyne.Resource),
+		apps:     make(map[string]fyne.Resource),
+		queued:   make(map[iconRequest]bool),
+		onUpdate: onUpdate,
+	}
+}
+
+// Get returns the icon for item, or nil if it has none or it hasn't been
+// looked up yet. Misses are cached too, so a window without an icon is only
+// looked up once.
+func (c *iconCache) Get(item CycleItem) fyne.Resource {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item.window != 0 {
+		if res, ok := c.windows[item.window]; ok {
+			return res
+		}
+	} else if res, ok := c.apps[appKey(item.appName, item.class)]; ok {
+		return res
+	}
+
+	req := iconRequest{window: item.window, appName: item.appName, class: item.class}
+	if !c.queued[req] {
+		c.queued[req] = true
+		c.queue = append(c.queue, req)
+		if !c.running {
+			c.running = true
+			go c.run()
+		}
+	}
+	return nil
+}
+
+// Retain drops icons of windows that are not in keep.
+func (c *iconCache) Retain(keep map[WindowID]bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.windows {
+		if !keep[id] {
+			delete(c.windows, id)
+		}
+	}
+}
+
+func (c *iconCache) run() {
+	found := false
+	for {
+		c.mu.Lock()
+		if len(c.queue) == 0 {
+			c.running = false
+			c.mu.Unlock()
+			break
+		}
+		req := c.queue[0]
+		c.queue = c.queue[1:]
+		c.mu.Unlock()
+
+		var res fyne.Resource
+		if req.window != 0 {
+			res = c.windowIcon(req.window)
+		}
+		if res == nil {
+			res = c.appIcon(req.appName, req.class)
+		}
+
+		c.mu.Lock()
+		delete(c.queued, req)
+		if req.window != 0 {
+			c.windows[req.window] = res
+		}
+		c.mu.Unlock()
+		found = found || res != nil
+	}
+
+	if found {
+		c.onUpdate()
+	}
+}
+
+func (c *iconCache) windowIcon(id WindowID) fyne.Resource {
+	if c.source == nil {
+		return nil
+	}
+	img, err := c.source.WindowIcon(id, c.size)
+	if err != nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		log.Printf("Failed to encode icon for window %s: %v\n", id, err)
+		return nil
+	}
+	// fyne caches textures by resource name, and window IDs are reused, so
+	// the name is taken from the icon itself.
+	h := fnv.New64a()
+	h.Write(buf.Bytes())
+	return fyne.NewStaticResource(fmt.Sprintf("icon-%016x.png", h.Sum64()), buf.Bytes())
+}
+
+func appKey(appName, class string) string {
+	return appName + "\x00" + class
+}
+
+// appIcon returns the desktop entry icon for an application, loading it on
+// first use. It is only called from run.
+func (c *iconCache) appIcon(appName, class string) fyne.Resource {
+	key := appKey(appName, class)
+	c.mu.Lock()
+	res, ok := c.apps[key]
+	c.mu.Unlock()
+	if ok {
+		return res
+	}
+
+	if path := desktopIconPath(appName, class); path != "" {
+		loaded, err := fyne.LoadResourceFromPath(path)
+		if err != nil {
+			log.Printf("Failed to load icon %s: %v\n", path, err)
+		} else {
+			res = loaded
+		}
+	}
+	c.mu.Lock()
+	c.apps[key] = res
+	c.mu.Unlock()
+	return res
+}
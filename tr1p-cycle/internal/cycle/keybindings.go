@@ -3,7 +3,6 @@ package cycle
 import (
 	"fmt"
 	"log"
-	"strings"
 	"sync"
 	"time"
 
@@ -12,71 +11,199 @@ import (
 	"golang.design/x/hotkey"
 )
 
-type Keybinds struct {
-	AddKeybind    string
-	RemoveKeybind string
-	CycleKeybind  string
+type hotkeyAction int
+
+const (
+	actionAdd hotkeyAction = iota
+	actionRemove
+	actionCycle
+	actionReverse
+	actionMove
+	actionSlot
+	actionMoveUp
+	actionMoveDown
+	actionSwitcher
+)
+
+// hotkeyEvent is what every registered hotkey feeds into Listen. Slot and
+// move up/down hotkeys have no ring of their own and act on the active ring.
+// mods are the keybind's modifiers; releasing them ends a cycle.
+type hotkeyEvent struct {
+	action hotkeyAction
+	ring   *CycleList
+	slot   int
+	mods   hotkey.Modifier
+}
+
+type boundHotkey struct {
+	hk *hotkey.Hotkey
+	ev hotkeyEvent
 }
 
 type KeybindListener struct {
-	cl           *CycleList
-	preview      *Preview
-	keybinds     Keybinds
-	stopChan     chan struct{}
-	addHotkey    *hotkey.Hotkey
-	removeHotkey *hotkey.Hotkey
-	cycleHotkey  *hotkey.Hotkey
-	mu           sync.Mutex
-	X            *xgb.Conn
+	rings         *Rings
+	preview       *Preview
+	switcher      *Switcher
+	timing        TimingConfig
+	stopChan      chan struct{}
+	hotkeys       []boundHotkey
+	events        chan hotkeyEvent
+	cycleActive   bool
+	cycleKeys     []xproto.Keycode
+	lastCycleTime time.Time
+	mu            sync.Mutex
+	X             *xgb.Conn
+	// keysyms holds every keysym the current keyboard layout produces.
+	keysyms map[xproto.Keysym]bool
 }
 
-func NewKeybindListener(cl *CycleList, keybinds Keybinds, preview *Preview) (*KeybindListener, error) {
-	addModifiers, addKey := parseKeybind(keybinds.AddKeybind)
-	removeModifiers, removeKey := parseKeybind(keybinds.RemoveKeybind)
-	cycleModifiers, cycleKey := parseKeybind(keybinds.CycleKeybind)
+func NewKeybindListener(rings *Rings, cfg Config, preview *Preview, switcher *Switcher) (*KeybindListener, error) {
+	kl := &KeybindListener{
+		rings:    rings,
+		preview:  preview,
+		switcher: switcher,
+		timing:   cfg.Timing,
+		stopChan: make(chan struct{}),
+		events:   make(chan hotkeyEvent),
+	}
 
-	addHotkey := hotkey.New(addModifiers, addKey)
-	removeHotkey := hotkey.New(removeModifiers, removeKey)
-	cycleHotkey := hotkey.New(cycleModifiers, cycleKey)
+	var err error
+	kl.X, err = xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %v", err)
+	}
+	if kl.keysyms, err = mappedKeysyms(kl.X); err != nil {
+		kl.X.Close()
+		return nil, err
+	}
+	if err := kl.bindAll(rings, cfg); err != nil {
+		kl.X.Close()
+		return nil, err
+	}
+	return kl, nil
+}
 
-	if err := addHotkey.Register(); err != nil {
-		return nil, fmt.Errorf("failed to register add hotkey: %v", err)
+func (kl *KeybindListener) bindAll(rings *Rings, cfg Config) error {
+	if err := kl.bindRing(rings.Get(DefaultRingName), cfg.Keybinds); err != nil {
+		return err
+	}
+	for _, rc := range cfg.Rings {
+		if err := kl.bindRing(rings.Get(rc.Name), rc.Keybinds); err != nil {
+			return err
+		}
+	}
+	for i, bind := range cfg.Keybinds.SlotKeybinds() {
+		if err := kl.register(fmt.Sprintf("slot %d", i+1), bind, hotkeyEvent{action: actionSlot, slot: i}); err != nil {
+			return err
+		}
+	}
+	if cfg.Keybinds.MoveUpKeybind != "" {
+		if err := kl.register("move up", cfg.Keybinds.MoveUpKeybind, hotkeyEvent{action: actionMoveUp}); err != nil {
+			return err
+		}
 	}
-	if err := removeHotkey.Register(); err != nil {
-		addHotkey.Unregister()
-		return nil, fmt.Errorf("failed to register remove hotkey: %v", err)
+	if cfg.Keybinds.MoveDownKeybind != "" {
+		if err := kl.register("move down", cfg.Keybinds.MoveDownKeybind, hotkeyEvent{action: actionMoveDown}); err != nil {
+			return err
+		}
 	}
-	if err := cycleHotkey.Register(); err != nil {
-		addHotkey.Unregister()
-		removeHotkey.Unregister()
-		return nil, fmt.Errorf("failed to register cycle hotkey: %v", err)
+	if cfg.Keybinds.SwitcherKeybind != "" {
+		if err := kl.register("switcher", cfg.Keybinds.SwitcherKeybind, hotkeyEvent{action: actionSwitcher}); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	X, err := xgb.NewConn()
+// mappedKeysyms returns every keysym produced by some key of the current
+// keyboard layout.
+func mappedKeysyms(X *xgb.Conn) (map[xproto.Keysym]bool, error) {
+	setup := xproto.Setup(X)
+	count := byte(setup.MaxKeycode - setup.MinKeycode + 1)
+	reply, err := xproto.GetKeyboardMapping(X, setup.MinKeycode, count).Reply()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to X server: %v", err)
+		return nil, fmt.Errorf("failed to get keyboard mapping: %v", err)
+	}
+	keysyms := make(map[xproto.Keysym]bool, len(reply.Keysyms))
+	for _, sym := range reply.Keysyms {
+		if sym != 0 {
+			keysyms[sym] = true
+		}
 	}
+	return keysyms, nil
+}
 
-	return &KeybindListener{
-		cl:           cl,
-		preview:      preview,
-		keybinds:     keybinds,
-		stopChan:     make(chan struct{}),
-		addHotkey:    addHotkey,
-		removeHotkey: removeHotkey,
-		cycleHotkey:  cycleHotkey,
-		X:            X,
-	}, nil
+// bindRing registers the ring's hotkeys. Empty keybinds are skipped;
+// Config.Validate has already insisted on the ones that are required.
+func (kl *KeybindListener) bindRing(cl *CycleList, kb Keybinds) error {
+	binds := []struct {
+		name    string
+		keybind string
+		action  hotkeyAction
+	}{
+		{"add", kb.AddKeybind, actionAdd},
+		{"remove", kb.RemoveKeybind, actionRemove},
+		{"cycle", kb.CycleKeybind, actionCycle},
+		{"reverse", kb.ReverseKeybind, actionReverse},
+		{"move", kb.MoveKeybind, actionMove},
+	}
+	for _, b := range binds {
+		if b.keybind == "" {
+			continue
+		}
+		name := fmt.Sprintf("%s %s", cl.name, b.name)
+		if err := kl.register(name, b.keybind, hotkeyEvent{action: b.action, ring: cl}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (kl *KeybindListener) Listen() {
-	var cycleActive bool
-	var lastCycleTime time.Time
+// register parses and registers a hotkey. On failure every hotkey registered
+// so far is released, so callers can simply return the error.
+func (kl *KeybindListener) register(name, keybind string, ev hotkeyEvent) error {
+	kb, err := ParseKeybind(keybind)
+	if err != nil {
+		kl.unregisterAll()
+		return fmt.Errorf("invalid %s keybind: %v", name, err)
+	}
+	// hotkey would look the key up as keycode 0, which X grabs as AnyKey,
+	// taking every key pressed with these modifiers.
+	if !kl.keysyms[xproto.Keysym(kb.Key)] {
+		kl.unregisterAll()
+		return fmt.Errorf("cannot register %s hotkey %s: no key on the current keyboard layout produces %s", name, kb, keyName(kb.Key))
+	}
+
+	hk := hotkey.New(kb.Modifiers(), kb.Key)
+	if err := hk.Register(); err != nil {
+		kl.unregisterAll()
+		return fmt.Errorf("failed to register %s hotkey %s: %v", name, kb, err)
+	}
+	ev.mods = kb.Mods
+	kl.hotkeys = append(kl.hotkeys, boundHotkey{hk: hk, ev: ev})
+	return nil
+}
+
+func (kl *KeybindListener) unregisterAll() {
+	for _, b := range kl.hotkeys {
+		b.hk.Unregister()
+	}
+	kl.hotkeys = nil
+}
 
+func (kl *KeybindListener) Listen() {
 	log.Println("Starting KeybindListener")
 
-	altCheckTicker := time.NewTicker(50 * time.Millisecond)
-	defer altCheckTicker.Stop()
+	// xgb has no XInput2 bindings for raw key events, so the keyboard is
+	// polled to see when the cycle modifiers are let go.
+	modifierTicker := time.NewTicker(kl.timing.ModifierPoll)
+	defer modifierTicker.Stop()
+
+	// Fan every hotkey into one channel so the loop below can select on
+	// them regardless of how many rings and slots are configured.
+	for _, b := range kl.hotkeys {
+		go kl.forward(b, kl.stopChan)
+	}
 
 	for {
 		select {
@@ -84,39 +211,24 @@ func (kl *KeybindListener) Listen() {
 			log.Println("KeybindListener stopped")
 			kl.X.Close()
 			return
-		case <-kl.addHotkey.Keydown():
-			log.Println("Add hotkey pressed")
-			handleAdd(kl.cl)
-			kl.preview.updateContent()
-		case <-kl.removeHotkey.Keydown():
-			log.Println("Remove hotkey pressed")
-			handleRemove(kl.cl)
-			kl.preview.updateContent()
-		case <-kl.cycleHotkey.Keydown():
-			kl.mu.Lock()
-			now := time.Now()
-			if !cycleActive || now.Sub(lastCycleTime) > 200*time.Millisecond {
-				log.Println("Cycle hotkey pressed")
-				cycleActive = true
-				lastCycleTime = now
-				if !kl.preview.IsVisible() {
-					log.Println("Cycle activated, showing preview")
-					kl.preview.ShowPreview()
-				}
-				log.Printf("Focusing next window. Cycle active: %v", cycleActive)
-				kl.cl.FocusNext()
-				kl.preview.updateContent()
-				kl.preview.ShowPreview()
-			}
-			kl.mu.Unlock()
-		case <-altCheckTicker.C:
+		case ev := <-kl.events:
+			kl.handle(ev)
+		case <-modifierTicker.C:
 			kl.mu.Lock()
-			altPressed := kl.isAltPressed()
-			if cycleActive {
-				if !altPressed {
-					cycleActive = false
-					log.Println("Alt released, hiding preview")
-					kl.preview.HidePreview()
+			if kl.cycleActive {
+				if !kl.cycleModifiersHeld() {
+					kl.cycleActive = false
+					if kl.preview.Navigating() {
+						// The preview ends the cycle itself on Enter or
+						// Escape, so that typing can go on without the
+						// modifiers.
+						log.Println("Cycle modifiers released while navigating, keeping preview open")
+					} else {
+						log.Println("Cycle modifiers released, hiding preview")
+						kl.preview.CommitSelection()
+						kl.rings.Active().EndCycle()
+						kl.preview.HidePreview()
+					}
 				} else {
 					// Update preview to show current active window
 					kl.preview.updateContent()
@@ -127,20 +239,138 @@ func (kl *KeybindListener) Listen() {
 	}
 }
 
-func (kl *KeybindListener) isAltPressed() bool {
+func (kl *KeybindListener) forward(b boundHotkey, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-b.hk.Keydown():
+			select {
+			case kl.events <- b.ev:
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+func (kl *KeybindListener) handle(ev hotkeyEvent) {
+	if ev.ring != nil {
+		kl.rings.SetActive(ev.ring)
+	}
+
+	switch ev.action {
+	case actionAdd:
+		log.Printf("Add hotkey pressed for ring %s", ev.ring.name)
+		handleAdd(ev.ring)
+		kl.preview.updateContent()
+	case actionRemove:
+		log.Printf("Remove hotkey pressed for ring %s", ev.ring.name)
+		handleRemove(ev.ring)
+		kl.preview.updateContent()
+	case actionCycle:
+		kl.cycle(ev, true)
+	case actionReverse:
+		kl.cycle(ev, false)
+	case actionMove:
+		log.Printf("Move hotkey pressed for ring %s", ev.ring.name)
+		kl.rings.MoveActiveWindow(ev.ring)
+		kl.preview.updateContent()
+	case actionSlot:
+		log.Printf("Slot %d hotkey pressed", ev.slot+1)
+		if err := kl.rings.Active().FocusSlot(ev.slot); err != nil {
+			log.Println(err)
+		}
+		kl.preview.updateContent()
+	case actionMoveUp:
+		log.Println("Move up hotkey pressed")
+		kl.rings.Active().MoveUp()
+		kl.preview.updateContent()
+	case actionMoveDown:
+		log.Println("Move down hotkey pressed")
+		kl.rings.Active().MoveDown()
+		kl.preview.updateContent()
+	case actionSwitcher:
+		log.Println("Switcher hotkey pressed")
+		kl.switcher.Show()
+	}
+}
+
+// cycle handles both cycle hotkeys; forward selects FocusNext, otherwise
+// FocusPrev.
+func (kl *KeybindListener) cycle(ev hotkeyEvent, forward bool) {
+	kl.mu.Lock()
+	defer kl.mu.Unlock()
+
+	cl := ev.ring
+	now := time.Now()
+	if kl.cycleActive && now.Sub(kl.lastCycleTime) <= kl.timing.CycleDebounce {
+		return
+	}
+
+	log.Printf("Cycle hotkey pressed (forward: %v)", forward)
+	if !kl.cycleActive {
+		kl.cycleKeys = kl.modifierKeycodes(ev.mods)
+	}
+	kl.cycleActive = true
+	kl.lastCycleTime = now
+	if !kl.preview.IsVisible() {
+		log.Println("Cycle activated, showing preview")
+		kl.preview.ShowPreview()
+	}
+	var err error
+	if forward {
+		err = cl.FocusNext()
+	} else {
+		err = cl.FocusPrev()
+	}
+	if err != nil {
+		log.Printf("Failed to cycle: %v", err)
+	}
+	kl.preview.updateContent()
+	kl.preview.ShowPreview()
+}
+
+// modifierKeycodes looks up the keys that currently produce mods in the X
+// modifier mapping, so that e.g. both Alt keys count, whatever the layout.
+// hotkey.Modifier bits are the X modifier mask bits, which index the
+// mapping: Shift, Lock, Control, then Mod1 to Mod5.
+func (kl *KeybindListener) modifierKeycodes(mods hotkey.Modifier) []xproto.Keycode {
+	reply, err := xproto.GetModifierMapping(kl.X).Reply()
+	if err != nil {
+		log.Printf("Failed to get modifier mapping: %v", err)
+		return nil
+	}
+
+	per := int(reply.KeycodesPerModifier)
+	var keys []xproto.Keycode
+	for i := 0; i < 8; i++ {
+		if mods&(1<<i) == 0 {
+			continue
+		}
+		for _, kc := range reply.Keycodes[i*per : (i+1)*per] {
+			if kc != 0 {
+				keys = append(keys, kc)
+			}
+		}
+	}
+	return keys
+}
+
+// cycleModifiersHeld reports whether any key of the modifiers that started
+// the cycle is still down. A cycle hotkey without modifiers ends right away.
+func (kl *KeybindListener) cycleModifiersHeld() bool {
 	state, err := xproto.QueryKeymap(kl.X).Reply()
 	if err != nil {
 		log.Printf("Failed to query keymap: %v", err)
 		return false
 	}
-
-	// Check for left Alt (usually keycode 64) and right Alt (usually keycode 108)
-	leftAlt := (state.Keys[64/8] & (1 << (64 % 8))) != 0
-	rightAlt := (state.Keys[108/8] & (1 << (108 % 8))) != 0
-
-	isPressed := leftAlt || rightAlt
-	log.Printf("isAltPressed check result: %v", isPressed)
-	return isPressed
+	for _, kc := range kl.cycleKeys {
+		if state.Keys[kc/8]&(1<<(kc%8)) != 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (kl *KeybindListener) Stop() {
@@ -152,60 +382,28 @@ func (kl *KeybindListener) Stop() {
 		kl.stopChan = nil
 	}
 
-	kl.addHotkey.Unregister()
-	kl.removeHotkey.Unregister()
-	kl.cycleHotkey.Unregister()
+	kl.unregisterAll()
 	kl.X.Close()
 }
 
-func parseKeybind(keybind string) ([]hotkey.Modifier, hotkey.Key) {
-	modifiers := []hotkey.Modifier{}
-	keys := strings.Split(keybind, "+")
-
-	var key hotkey.Key
-	for _, k := range keys {
-		switch strings.ToLower(k) {
-		case "alt":
-			modifiers = append(modifiers, hotkey.Mod1)
-		case "shift":
-			modifiers = append(modifiers, hotkey.ModShift)
-		case "ctrl":
-			modifiers = append(modifiers, hotkey.ModCtrl)
-		case "e":
-			key = hotkey.KeyE
-		case "d":
-			key = hotkey.KeyD
-		case "tab":
-			key = tab
-		}
-	}
-
-	return modifiers, key
-}
-
 func handleAdd(cl *CycleList) {
-	windowID, processName, _, err := getActiveWindow()
+	win, err := cl.backend.ActiveWindow()
 	if err != nil {
 		log.Printf("Failed to get active window: %v\n", err)
 		return
 	}
-	cl.Add(processName)
-	log.Printf("Added window: %s (ID: %s) to the cycle list.\n", processName, windowID)
+	cl.Add(win.Title)
+	log.Printf("Added window: %s (ID: %s) to the cycle list.\n", win.Title, win.ID)
 	cl.PrintItems()
 }
 
 func handleRemove(cl *CycleList) {
-	windowID, processName, _, err := getActiveWindow()
+	win, err := cl.backend.ActiveWindow()
 	if err != nil {
 		log.Printf("Failed to get active window: %v\n", err)
 		return
 	}
-	cl.Remove(processName)
-	log.Printf("Removed window: %s (ID: %s) from the cycle list.\n", processName, windowID)
+	cl.Remove(win.Title)
+	log.Printf("Removed window: %s (ID: %s) from the cycle list.\n", win.Title, win.ID)
 	cl.PrintItems()
 }
-
-const (
-	tab = 0xff09
-	alt = 0xffe9
-)
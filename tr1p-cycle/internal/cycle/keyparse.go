@@ -0,0 +1,198 @@
+package cycle
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.design/x/hotkey"
+)
+
+// Keybind is a parsed hotkey such as "Alt+Shift+Tab".
+type Keybind struct {
+	Mods hotkey.Modifier
+	Key  hotkey.Key
+}
+
+// Modifiers splits Mods into the form hotkey.New expects.
+func (k Keybind) Modifiers() []hotkey.Modifier {
+	var mods []hotkey.Modifier
+	for _, m := range modifierNames {
+		if k.Mods&m.mod != 0 {
+			mods = append(mods, m.mod)
+		}
+	}
+	return mods
+}
+
+// String returns the canonical form, so "shift+alt+e" becomes
+// "Alt+Shift+E".
+func (k Keybind) String() string {
+	var parts []string
+	for _, m := range modifierNames {
+		if k.Mods&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, keyName(k.Key)), "+")
+}
+
+// Modifiers in canonical display order. The first name is canonical, the
+// rest are accepted aliases.
+var modifierNames = []struct {
+	mod     hotkey.Modifier
+	name    string
+	aliases []string
+}{
+	{hotkey.ModCtrl, "Ctrl", []string{"control"}},
+	{hotkey.Mod1, "Alt", []string{"mod1"}},
+	{hotkey.ModShift, "Shift", nil},
+	{hotkey.Mod4, "Super", []string{"meta", "win", "mod4"}},
+	{hotkey.Mod2, "Mod2", nil},
+	{hotkey.Mod3, "Mod3", nil},
+	{hotkey.Mod5, "Mod5", nil},
+}
+
+// namedKeys maps key names to X keysyms (see X11/keysymdef.h). The hotkey
+// package's own constants can't be used for these: several, like KeyTab
+// and Key0, have the wrong keysym.
+var namedKeys = []struct {
+	keysym  hotkey.Key
+	name    string
+	aliases []string
+}{
+	{0xff09, "Tab", nil},
+	{0x0020, "Space", nil},
+	{0xff0d, "Return", []string{"enter"}},
+	{0xff1b, "Escape", []string{"esc"}},
+	{0xff08, "BackSpace", nil},
+	{0xffff, "Delete", []string{"del"}},
+	{0xff63, "Insert", []string{"ins"}},
+	{0xff50, "Home", nil},
+	{0xff57, "End", nil},
+	{0xff55, "PageUp", []string{"prior"}},
+	{0xff56, "PageDown", []string{"next"}},
+	{0xff51, "Left", nil},
+	{0xff52, "Up", nil},
+	{0xff53, "Right", nil},
+	{0xff54, "Down", nil},
+	{0x002d, "Minus", []string{"-"}},
+	{0x003d, "Equal", []string{"="}},
+	{0x002b, "Plus", nil},
+	{0x002c, "Comma", []string{","}},
+	{0x002e, "Period", []string{"."}},
+	{0x002f, "Slash", []string{"/"}},
+	{0x005c, "Backslash", []string{"\\"}},
+	{0x003b, "Semicolon", []string{";"}},
+	{0x0027, "Apostrophe", []string{"'"}},
+	{0x0060, "Grave", []string{"`"}},
+	{0x005b, "BracketLeft", []string{"["}},
+	{0x005d, "BracketRight", []string{"]"}},
+}
+
+const (
+	keysymF1 = 0xffbe
+	maxFKey  = 24
+)
+
+// ParseKeybind parses modifiers and exactly one key joined by "+". Names are
+// case-insensitive. Letters, digits, F1-F24 and the names in namedKeys are
+// accepted as keys.
+func ParseKeybind(s string) (Keybind, error) {
+	if strings.TrimSpace(s) == "" {
+		return Keybind{}, fmt.Errorf("keybind is empty")
+	}
+
+	var kb Keybind
+	haveKey := false
+
+	for _, tok := range strings.Split(s, "+") {
+		tok = strings.TrimSpace(tok)
+		if tok == "" {
+			return Keybind{}, fmt.Errorf("keybind %q: empty key name (use \"Plus\" for the + key)", s)
+		}
+
+		if mod, ok := lookupModifier(tok); ok {
+			if kb.Mods&mod != 0 {
+				return Keybind{}, fmt.Errorf("keybind %q: modifier %s given twice", s, tok)
+			}
+			kb.Mods |= mod
+			continue
+		}
+
+		key, ok := lookupKey(tok)
+		if !ok {
+			return Keybind{}, fmt.Errorf("keybind %q: unknown key or modifier %q", s, tok)
+		}
+		if haveKey {
+			return Keybind{}, fmt.Errorf("keybind %q: more than one key (%s and %s)", s, keyName(kb.Key), tok)
+		}
+		kb.Key = key
+		haveKey = true
+	}
+
+	if !haveKey {
+		return Keybind{}, fmt.Errorf("keybind %q: no key, only modifiers", s)
+	}
+	return kb, nil
+}
+
+func lookupModifier(tok string) (hotkey.Modifier, bool) {
+	for _, m := range modifierNames {
+		if strings.EqualFold(tok, m.name) {
+			return m.mod, true
+		}
+		for _, alias := range m.aliases {
+			if strings.EqualFold(tok, alias) {
+				return m.mod, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func lookupKey(tok string) (hotkey.Key, bool) {
+	if len(tok) == 1 {
+		c := strings.ToLower(tok)[0]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' {
+			// Latin-1 keysyms equal their lowercase ASCII codes.
+			return hotkey.Key(c), true
+		}
+	}
+
+	var n int
+	if _, err := fmt.Sscanf(strings.ToLower(tok), "f%d", &n); err == nil && fmt.Sprintf("f%d", n) == strings.ToLower(tok) {
+		if n >= 1 && n <= maxFKey {
+			return hotkey.Key(keysymF1 + n - 1), true
+		}
+		return 0, false
+	}
+
+	for _, k := range namedKeys {
+		if strings.EqualFold(tok, k.name) {
+			return k.keysym, true
+		}
+		for _, alias := range k.aliases {
+			if strings.EqualFold(tok, alias) {
+				return k.keysym, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func keyName(key hotkey.Key) string {
+	switch {
+	case key >= 'a' && key <= 'z':
+		return strings.ToUpper(string(rune(key)))
+	case key >= '0' && key <= '9':
+		return string(rune(key))
+	case key >= keysymF1 && key < keysymF1+maxFKey:
+		return fmt.Sprintf("F%d", key-keysymF1+1)
+	}
+	for _, k := range namedKeys {
+		if k.keysym == key {
+			return k.name
+		}
+	}
+	return fmt.Sprintf("0x%04x", uint16(key))
+}
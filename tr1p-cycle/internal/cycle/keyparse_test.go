@@ -0,0 +1,53 @@
+package cycle
+
+import "testing"
+
+func TestParseKeybind(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Alt+Tab", "Alt+Tab"},
+		{"shift+alt+e", "Alt+Shift+E"},
+		{"Alt+Shift+Tab", "Alt+Shift+Tab"},
+		{"control+PLUS", "Ctrl+Plus"},
+		{"Meta+-", "Super+Minus"},
+		{"win+1", "Super+1"},
+		{"mod1+enter", "Alt+Return"},
+		{"ctrl+F12", "Ctrl+F12"},
+		{" Alt + Space ", "Alt+Space"},
+		{"mod5+Left", "Mod5+Left"},
+	}
+	for _, tt := range tests {
+		kb, err := ParseKeybind(tt.in)
+		if err != nil {
+			t.Errorf("ParseKeybind(%q): %v", tt.in, err)
+			continue
+		}
+		if got := kb.String(); got != tt.want {
+			t.Errorf("ParseKeybind(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+		again, err := ParseKeybind(kb.String())
+		if err != nil || again != kb {
+			t.Errorf("ParseKeybind(%q) does not round-trip: %+v, %v", kb.String(), again, err)
+		}
+	}
+}
+
+func TestParseKeybindErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"  ",
+		"Alt",
+		"Alt+X+Y",
+		"Alt++",
+		"Hyper+E",
+		"Alt+Alt+E",
+		"F0",
+		"F25",
+	} {
+		if kb, err := ParseKeybind(in); err == nil {
+			t.Errorf("ParseKeybind(%q) = %s, want an error", in, kb)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package cycle
+
+import (
+	"fmt"
+	"log"
+)
+
+// Rect is an area of the screen in pixels, relative to the root window.
+type Rect struct {
+	X, Y          int
+	Width, Height int
+}
+
+func (r Rect) contains(x, y int) bool {
+	return x >= r.X && x < r.X+r.Width && y >= r.Y && y < r.Y+r.Height
+}
+
+// Monitor is one output of the screen, named after it, e.g. "DP-1".
+type Monitor struct {
+	Name string
+	Rect
+}
+
+// MonitorSource is implemented by backends that know the monitor layout and
+// where windows are, and can move windows. Without it the preview is placed
+// by the window manager and cycling ignores monitors.
+type MonitorSource interface {
+	Monitors() ([]Monitor, error)
+	WindowGeometry(id WindowID) (Rect, error)
+	MoveWindow(id WindowID, x, y int) error
+}
+
+// monitorOf returns the monitor holding the centre of r, falling back to the
+// one that overlaps it most, so windows straddling two monitors belong to
+// one of them.
+func monitorOf(monitors []Monitor, r Rect) (Monitor, bool) {
+	cx, cy := r.X+r.Width/2, r.Y+r.Height/2
+	best, bestArea := -1, 0
+	for i, m := range monitors {
+		if m.contains(cx, cy) {
+			return m, true
+		}
+		w := min(r.X+r.Width, m.X+m.Width) - max(r.X, m.X)
+		h := min(r.Y+r.Height, m.Y+m.Height) - max(r.Y, m.Y)
+		if w > 0 && h > 0 && w*h > bestArea {
+			best, bestArea = i, w*h
+		}
+	}
+	if best < 0 {
+		return Monitor{}, false
+	}
+	return monitors[best], true
+}
+
+// activeMonitor returns the monitor of the given window.
+func activeMonitor(ms MonitorSource, id WindowID) (Monitor, []Monitor, error) {
+	monitors, err := ms.Monitors()
+	if err != nil {
+		return Monitor{}, nil, err
+	}
+	geom, err := ms.WindowGeometry(id)
+	if err != nil {
+		return Monitor{}, nil, err
+	}
+	m, ok := monitorOf(monitors, geom)
+	if !ok {
+		return Monitor{}, nil, fmt.Errorf("window %s is not on any monitor", id)
+	}
+	return m, monitors, nil
+}
+
+// centerOn moves win so that it is centred on the monitor showing target.
+func centerOn(ms MonitorSource, win, target WindowID) error {
+	m, _, err := activeMonitor(ms, target)
+	if err != nil {
+		return err
+	}
+	geom, err := ms.WindowGeometry(win)
+	if err != nil {
+		return err
+	}
+	return ms.MoveWindow(win, m.X+(m.Width-geom.Width)/2, m.Y+(m.Height-geom.Height)/2)
+}
+
+// keepActiveMonitor drops the ring's windows that aren't on the same monitor
+// as the active window from open. If the monitors can't be worked out, open
+// is left alone so that cycling still works. Called with c.mu held.
+func (c *CycleList) keepActiveMonitor(open map[WindowID]bool) {
+	ms, ok := c.backend.(MonitorSource)
+	if !ok {
+		return
+	}
+	active := c.current.window
+	if win, err := c.backend.ActiveWindow(); err == nil && win.ID != 0 {
+		active = win.ID
+	}
+	m, monitors, err := activeMonitor(ms, active)
+	if err != nil {
+		log.Printf("Failed to find the active monitor: %v\n", err)
+		return
+	}
+
+	for id := range c.track {
+		if !open[id] {
+			continue
+		}
+		geom, err := ms.WindowGeometry(id)
+		if err != nil {
+			continue
+		}
+		if on, ok := monitorOf(monitors, geom); ok && on.Name != m.Name {
+			delete(open, id)
+		}
+	}
+}
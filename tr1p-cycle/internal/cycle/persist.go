@@ -0,0 +1,311 @@
+package cycle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SavedItem is how a CycleItem is written to disk. Window IDs and PIDs don't
+// survive a restart, so items are re-attached by matching these fields
+// against live windows.
+type SavedItem struct {
+	Class   string `json:"class,omitempty"`
+	AppName string `json:"app_name,omitempty"`
+	Title   string `json:"title,omitempty"`
+	// TitlePattern, when set, is a regular expression the window title must
+	// match. It is never generated, only hand-edited, and is preserved.
+	TitlePattern string `json:"title_pattern,omitempty"`
+}
+
+type savedList struct {
+	Items []SavedItem `json:"items"`
+}
+
+// Store reads and writes the cycle list to a JSON file.
+type Store struct {
+	path string
+	// writing serializes writes to the file.
+	writing sync.Mutex
+	// mu guards the items handed to Queue that are not written yet.
+	mu      sync.Mutex
+	pending []SavedItem
+	queued  bool
+}
+
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// DefaultStorePath returns $XDG_STATE_HOME/tr1p-cycle/ring.json, falling
+// back to ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultStorePath() (string, error) {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine state directory: %v", err)
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "tr1p-cycle", "ring.json"), nil
+}
+
+// Load returns the saved items, or none if nothing has been saved yet.
+func (s *Store) Load() ([]SavedItem, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", s.path, err)
+	}
+
+	var list savedList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", s.path, err)
+	}
+	return list.Items, nil
+}
+
+// Save replaces the file atomically so a crash never leaves it truncated.
+// Items queued before are dropped, as items are newer.
+func (s *Store) Save(items []SavedItem) error {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+	s.takePending()
+	return s.write(items)
+}
+
+// Queue saves items in the background. When several lists are queued
+// before the first is written, only the last one is.
+func (s *Store) Queue(items []SavedItem) {
+	s.mu.Lock()
+	start := !s.queued
+	s.pending = items
+	s.queued = true
+	s.mu.Unlock()
+
+	if start {
+		go func() {
+			if err := s.Flush(); err != nil {
+				log.Printf("Failed to save cycle list: %v\n", err)
+			}
+		}()
+	}
+}
+
+// Flush writes the items last queued, if they haven't been written yet.
+func (s *Store) Flush() error {
+	s.writing.Lock()
+	defer s.writing.Unlock()
+	items, ok := s.takePending()
+	if !ok {
+		return nil
+	}
+	return s.write(items)
+}
+
+func (s *Store) takePending() ([]SavedItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, ok := s.pending, s.queued
+	s.pending, s.queued = nil, false
+	return items, ok
+}
+
+func (s *Store) write(items []SavedItem) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(savedList{Items: items}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// Restore loads the saved list, attaches entries to matching live windows
+// and keeps the rest as placeholders. From then on every change to the list
+// is written back to store.
+func (c *CycleList) Restore(store *Store) error {
+	saved, err := store.Load()
+	if err != nil {
+		return err
+	}
+
+	windows, err := c.backend.ListWindows()
+	if err != nil {
+		log.Printf("Failed to list windows, restoring placeholders only: %v\n", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, s := range saved {
+		item := &CycleItem{title: s.Title, name: s.Title, appName: s.AppName, class: s.Class, desktop: AllDesktops}
+		if s.TitlePattern != "" {
+			re, err := regexp.Compile(s.TitlePattern)
+			if err != nil {
+				log.Printf("Ignoring invalid title pattern %q: %v\n", s.TitlePattern, err)
+			} else {
+				item.titlePattern = re
+			}
+		}
+		c.appendItem(item)
+	}
+	c.attachPlaceholders(windows, nil)
+	c.store = store
+	log.Printf("Restored %d items from %s\n", len(saved), store.path)
+	return nil
+}
+
+// attachPlaceholders binds detached items to the best matching untracked
+// windows. Ghosts only take windows that are not in seen, so closing a window
+// doesn't hand its slot to another one that was already open. It reports
+// whether anything was attached.
+func (c *CycleList) attachPlaceholders(windows []WindowInfo, seen map[WindowID]bool) bool {
+	if c.head == nil {
+		return false
+	}
+
+	attached := false
+	item := c.head
+	for {
+		if item.window == 0 {
+			best, bestScore := -1, 0
+			for i, w := range windows {
+				if _, tracked := c.track[w.ID]; tracked {
+					continue
+				}
+				if item.ghost && seen[w.ID] {
+					continue
+				}
+				if score := item.matchScore(w); score > bestScore {
+					best, bestScore = i, score
+				}
+			}
+			if best >= 0 {
+				c.attach(item, windows[best])
+				attached = true
+				log.Printf("Attached placeholder to window: %s (Window ID: %s)\n", item.title, item.window)
+			}
+		}
+		item = item.next
+		if item == c.head {
+			break
+		}
+	}
+	return attached
+}
+
+func (c *CycleList) attach(item *CycleItem, w WindowInfo) {
+	item.window = w.ID
+	item.ghost = false
+	item.process = w.PID
+	item.title = w.Title
+	item.name = w.Title
+	item.appName = w.AppName
+	item.class = w.Class
+	item.desktop = w.Desktop
+	item.state = w.State
+	c.track[w.ID] = item
+	c.emit(ItemAdded, item)
+}
+
+// matchScore reports how well w fits a detached item; 0 means no match.
+// Class, application name and title pattern are requirements when known,
+// and an identical title is preferred over other candidates.
+func (item *CycleItem) matchScore(w WindowInfo) int {
+	if item.class == "" && item.appName == "" && item.titlePattern == nil {
+		if item.title != "" && item.title == w.Title {
+			return 1
+		}
+		return 0
+	}
+	if item.class != "" && !strings.EqualFold(item.class, w.Class) {
+		return 0
+	}
+	if item.appName != "" && item.appName != w.AppName {
+		return 0
+	}
+	if item.titlePattern != nil && !item.titlePattern.MatchString(w.Title) {
+		return 0
+	}
+
+	score := 1
+	if item.title == w.Title {
+		score++
+	}
+	return score
+}
+
+// Save writes the list to its store right away. The list is already saved
+// after every change, so this is only needed to recreate a lost state file.
+func (c *CycleList) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.store == nil {
+		return fmt.Errorf("ring %s is not persisted", c.name)
+	}
+	return c.store.Save(c.savedItems())
+}
+
+// save queues the list for writing to the store, if any, so that the file
+// isn't written with c.mu held. Callers must hold c.mu.
+func (c *CycleList) save() {
+	if c.store == nil {
+		return
+	}
+	c.store.Queue(c.savedItems())
+}
+
+// flush waits for changes queued by save to be written.
+func (c *CycleList) flush() {
+	c.mu.Lock()
+	store := c.store
+	c.mu.Unlock()
+
+	if store == nil {
+		return
+	}
+	if err := store.Flush(); err != nil {
+		log.Printf("Failed to save cycle list: %v\n", err)
+	}
+}
+
+// savedItems returns the list as it is stored. Callers must hold c.mu.
+func (c *CycleList) savedItems() []SavedItem {
+	var items []SavedItem
+	if c.head != nil {
+		item := c.head
+		for {
+			s := SavedItem{Class: item.class, AppName: item.appName, Title: item.title}
+			if item.titlePattern != nil {
+				s.TitlePattern = item.titlePattern.String()
+			}
+			items = append(items, s)
+			item = item.next
+			if item == c.head {
+				break
+			}
+		}
+	}
+	return items
+}
@@ -0,0 +1,93 @@
+package cycle
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMatchScore(t *testing.T) {
+	win := WindowInfo{Class: "Alacritty", AppName: "alacritty", Title: "~/src"}
+	tests := []struct {
+		name string
+		item CycleItem
+		want int
+	}{
+		{"title only, same", CycleItem{title: "~/src"}, 1},
+		{"title only, different", CycleItem{title: "~/docs"}, 0},
+		{"nothing known", CycleItem{}, 0},
+		{"class ignores case", CycleItem{class: "alacritty", title: "~/docs"}, 1},
+		{"class and title", CycleItem{class: "Alacritty", title: "~/src"}, 2},
+		{"other class", CycleItem{class: "XTerm", title: "~/src"}, 0},
+		{"other app", CycleItem{appName: "xterm", title: "~/src"}, 0},
+		{"pattern matches", CycleItem{appName: "alacritty", titlePattern: regexp.MustCompile("^~/")}, 1},
+		{"pattern fails", CycleItem{appName: "alacritty", titlePattern: regexp.MustCompile("^/")}, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.item.matchScore(win); got != tt.want {
+			t.Errorf("%s: matchScore = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStoreLoadMissing(t *testing.T) {
+	items, err := NewStore(filepath.Join(t.TempDir(), "ring.json")).Load()
+	if err != nil || items != nil {
+		t.Errorf("Load() of a missing file = %v, %v; want nothing", items, err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "ring.json")
+	store := NewStore(path)
+	saved := []SavedItem{
+		{Class: "Alacritty", AppName: "alacritty", Title: "~/src", TitlePattern: "^~/"},
+		{Class: "Firefox", AppName: "firefox", Title: "Mozilla Firefox"},
+		{Class: "Slack", AppName: "slack", Title: "Slack"},
+	}
+	if err := store.Save(saved); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	backend := NewFakeBackend(
+		WindowInfo{ID: 1, Class: "Firefox", AppName: "firefox", Title: "Mozilla Firefox"},
+		WindowInfo{ID: 2, Class: "Alacritty", AppName: "alacritty", Title: "~/docs"},
+		WindowInfo{ID: 3, Class: "Alacritty", AppName: "alacritty", Title: "/tmp"},
+	)
+	cl := NewCycleList(DefaultRingName, backend, DefaultConfig())
+	if err := cl.Restore(store); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	items, _ := cl.GetItems()
+	want := []WindowID{2, 1, 0}
+	got := make([]WindowID, len(items))
+	for i, item := range items {
+		got[i] = item.window
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored windows = %v, want %v", got, want)
+	}
+
+	// Changes are written back, with the hand-written pattern kept and the
+	// placeholder still in its slot.
+	cl.MoveItem(2, 0)
+	cl.flush()
+	reloaded, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	wantSaved := []SavedItem{
+		saved[2],
+		{Class: "Alacritty", AppName: "alacritty", Title: "~/docs", TitlePattern: "^~/"},
+		saved[1],
+	}
+	if !reflect.DeepEqual(reloaded, wantSaved) {
+		t.Errorf("saved items = %+v, want %+v", reloaded, wantSaved)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
@@ -3,48 +3,89 @@ package cycle
 import (
 	"image/color"
 	"log"
+	"strconv"
 	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver"
 	"fyne.io/fyne/v2/driver/desktop"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
 )
 
 type Preview struct {
-	content *fyne.Container
-	window  fyne.Window
-	visible bool
-	mu      sync.Mutex
-	cl      *CycleList
+	content  *fyne.Container
+	window   fyne.Window
+	visible  bool
+	dragging bool
+	mu       sync.Mutex
+	rings    *Rings
+	cfg      PreviewConfig
+	thumbs   *thumbnailViews
+	icons    *iconCache
+
+	// Keyboard navigation state, reset whenever the preview is hidden.
+	// selected indexes the filtered items; -1 follows the ring's current
+	// item. origin is the window that was active when the preview opened.
+	filter   string
+	selected int
+	origin   WindowID
 }
 
-func NewPreview(app fyne.App, cl *CycleList) *Preview {
+func NewPreview(app fyne.App, rings *Rings, cfg PreviewConfig) *Preview {
 	log.Println("Creating new Preview")
 	drv := app.Driver()
 	if drv, ok := drv.(desktop.Driver); ok {
 		w := drv.CreateSplashWindow()
 		log.Println("Splash window created")
 		w.RequestFocus()
-		overlayColor := theme.Color(theme.ColorNameBackground)
-		r, g, b, _ := overlayColor.RGBA()
-		overlay := canvas.NewRectangle(color.NRGBA{
-			R: uint8(r >> 8),
-			G: uint8(g >> 8),
-			B: uint8(b >> 8),
-			A: 230,
-		})
+		var overlay *canvas.Rectangle
+		if bg, err := parseColor(cfg.Background); err == nil {
+			overlay = canvas.NewRectangle(bg)
+		} else {
+			overlayColor := theme.Color(theme.ColorNameBackground)
+			r, g, b, _ := overlayColor.RGBA()
+			overlay = canvas.NewRectangle(color.NRGBA{
+				R: uint8(r >> 8),
+				G: uint8(g >> 8),
+				B: uint8(b >> 8),
+				A: 230,
+			})
+		}
 		content := container.NewStack(overlay)
 		w.SetContent(content)
-		w.Resize(fyne.NewSize(500, 400))
-		return &Preview{
-			content: content,
-			window:  w,
-			visible: false,
-			cl:      cl,
+		w.Resize(fyne.NewSize(cfg.Width, cfg.Height))
+		p := &Preview{
+			content:  content,
+			window:   w,
+			visible:  false,
+			rings:    rings,
+			cfg:      cfg,
+			selected: -1,
+		}
+		w.Canvas().SetOnTypedKey(p.typedKey)
+		w.Canvas().SetOnTypedRune(p.typedRune)
+		p.addModifiedShortcuts(w.Canvas())
+		refresh := func() {
+			if p.IsVisible() {
+				p.updateContent()
+			}
 		}
+		if cfg.Icons {
+			source, _ := rings.backend.(WindowIconSource)
+			p.icons = newIconCache(source, int(cfg.IconSize), refresh)
+		}
+		if cfg.Thumbnails || cfg.Layout == LayoutGrid {
+			if capturer, ok := rings.backend.(WindowCapturer); ok {
+				p.thumbs = newThumbnailViews(newThumbnailCache(capturer, int(cfg.ThumbnailWidth), int(cfg.ThumbnailHeight), cfg.ThumbnailRefresh, refresh))
+			} else {
+				log.Println("Window backend cannot capture windows, thumbnails disabled")
+			}
+		}
+		return p
 	}
 	log.Println("Failed to create Preview: driver does not support desktop")
 	return nil
@@ -57,10 +98,20 @@ func (p *Preview) ShowPreview() {
 		return
 	}
 	p.mu.Lock()
+	opening := !p.visible
+	if opening {
+		if win, err := p.rings.backend.ActiveWindow(); err == nil {
+			p.origin = win.ID
+		}
+	}
+	origin := p.origin
 	p.visible = true
 	p.mu.Unlock()
 	p.updateContent()
 	p.window.Show()
+	if opening {
+		centerWindow(p.window, p.rings.backend, origin)
+	}
 	p.window.RequestFocus()
 }
 
@@ -72,8 +123,14 @@ func (p *Preview) HidePreview() {
 	}
 	p.mu.Lock()
 	p.visible = false
+	p.filter = ""
+	p.selected = -1
+	p.origin = 0
 	p.mu.Unlock()
 	p.window.Hide()
+	if p.thumbs != nil {
+		p.thumbs.cache.Pause()
+	}
 	log.Println("Preview window hidden")
 }
 
@@ -84,26 +141,99 @@ func (p *Preview) IsVisible() bool {
 }
 
 func (p *Preview) updateContent() {
-	items := p.cl.GetItems()
-	currentItem := p.cl.GetCurrentItem()
-	content := generatePreviewContent(items, currentItem)
+	p.mu.Lock()
+	dragging := p.dragging
+	p.mu.Unlock()
+	if dragging {
+		// Rebuilding now would throw away the row being dragged.
+		return
+	}
+
+	active, items, slots, current := p.view()
+	keep := make(map[WindowID]bool, len(items))
+	for _, item := range items {
+		keep[item.window] = true
+	}
+	if p.thumbs != nil {
+		p.thumbs.Retain(keep)
+	}
+	if p.icons != nil {
+		p.icons.Retain(keep)
+	}
+	onDrag := func() {
+		p.mu.Lock()
+		p.dragging = true
+		p.mu.Unlock()
+	}
+	onDrop := func(from, to int) {
+		p.mu.Lock()
+		p.dragging = false
+		p.mu.Unlock()
+		if from != to {
+			// Rows may be filtered, so map them back to ring slots.
+			to = min(max(to, 0), len(slots)-1)
+			active.MoveItem(slots[from], slots[to])
+		}
+		p.updateContent()
+	}
+	var content fyne.CanvasObject = generatePreviewContent(items, slots, current, p.cfg, p.thumbs, p.icons, onDrag, onDrop)
+
+	header := container.NewVBox()
+	if rings := p.rings.All(); len(rings) > 1 {
+		header.Add(generateRingHeader(rings, active))
+	}
+	p.mu.Lock()
+	filter := p.filter
+	p.mu.Unlock()
+	if filter != "" {
+		filterText := canvas.NewText("Filter: "+filter, theme.Color(theme.ColorNamePrimary))
+		filterText.TextSize = 14
+		header.Add(container.NewPadded(filterText))
+	}
+	if len(header.Objects) > 0 {
+		content = container.NewBorder(header, nil, nil, nil, content)
+	}
 	p.content.Objects = []fyne.CanvasObject{p.content.Objects[0], content}
 	p.content.Refresh()
 }
 
-func generatePreviewContent(items []CycleItem, currentItem *CycleItem) *fyne.Container {
+// generateRingHeader names every ring, with the active one highlighted.
+func generateRingHeader(rings []*CycleList, active *CycleList) *fyne.Container {
+	header := container.NewHBox()
+	for _, cl := range rings {
+		name := canvas.NewText(cl.Name(), theme.Color(theme.ColorNamePlaceHolder))
+		name.TextSize = 14
+		if cl == active {
+			name.Color = theme.Color(theme.ColorNamePrimary)
+			name.TextStyle = fyne.TextStyle{Bold: true}
+		}
+		header.Add(name)
+	}
+	return container.NewPadded(header)
+}
+
+// generatePreviewContent lists items in ring order, numbered by their ring
+// slots from slots, with the item at index current highlighted. Rows can be
+// dragged to reorder the ring; onDrag fires when a drag starts and onDrop
+// when it ends. thumbs and icons are nil when thumbnails or icons are off.
+func generatePreviewContent(items []CycleItem, slots []int, current int, cfg PreviewConfig, thumbs *thumbnailViews, icons *iconCache, onDrag func(), onDrop func(from, to int)) *fyne.Container {
 	if len(items) == 0 {
 		fgColor := theme.Color(theme.ColorNameForeground)
 		emptyText := canvas.NewText("The cycle list is empty.", fgColor)
 		emptyText.TextSize = 18
 		return container.NewCenter(emptyText)
 	}
+	if cfg.Layout == LayoutGrid {
+		return generatePreviewGrid(items, slots, current, cfg, thumbs, icons)
+	}
+
+	highlight, _ := parseColor(cfg.Highlight)
+	highlightText, _ := parseColor(cfg.HighlightText)
 
 	listContainer := container.NewVBox()
 
 	for i, item := range items {
-		isActive := &item == currentItem
-		isTopItem := i == 0
+		isActive := i == current
 
 		row := container.NewHBox()
 
@@ -115,36 +245,62 @@ func generatePreviewContent(items []CycleItem, currentItem *CycleItem) *fyne.Con
 		prefix.TextSize = 20
 		row.Add(prefix)
 
+		// Slot number, matching the slot hotkeys
+		slot := canvas.NewText(" ", theme.Color(theme.ColorNamePlaceHolder))
+		if slots[i] < MaxSlots {
+			slot.Text = strconv.Itoa(slots[i] + 1)
+		}
+		slot.TextSize = 16
+		slot.TextStyle = fyne.TextStyle{Monospace: true}
+		if isActive {
+			slot.Color = highlightText
+		}
+		row.Add(slot)
+
+		if thumbs != nil {
+			row.Add(thumbnailImage(thumbs, item, cfg))
+		}
+		if icons != nil {
+			row.Add(iconImage(icons, item, cfg))
+		}
+
 		// Add item details
 		details := container.NewVBox()
 
-		titleText := canvas.NewText(item.title, theme.Color(theme.ColorNameForeground))
+		titleColor := theme.Color(theme.ColorNameForeground)
+		if item.window == 0 {
+			// Restored item still waiting for its window to appear
+			titleColor = theme.Color(theme.ColorNamePlaceHolder)
+		}
+		titleText := canvas.NewText(item.title, titleColor)
 		titleText.TextSize = 16
 		if isActive {
-			titleText.Color = theme.Color(theme.ColorNamePrimary)
-			titleText.TextStyle = fyne.TextStyle{Bold: true}
-		}
-		if isTopItem {
-			titleText.Color = color.NRGBA{R: 0, G: 0, B: 139, A: 255} // Dark Blue
+			titleText.Color = highlightText
 			titleText.TextStyle = fyne.TextStyle{Bold: true}
 		}
 		details.Add(titleText)
 
-		appNameText := canvas.NewText(item.appName, theme.Color(theme.ColorNamePlaceHolder))
+		subtitle := item.appName
+		if item.desktop != AllDesktops {
+			// Workspaces are numbered from one, as pagers show them.
+			subtitle += "  ·  workspace " + strconv.Itoa(item.desktop+1)
+		}
+		appNameText := canvas.NewText(subtitle, theme.Color(theme.ColorNamePlaceHolder))
 		appNameText.TextSize = 12
-		if isTopItem {
-			appNameText.Color = color.NRGBA{R: 0, G: 0, B: 139, A: 255} // Dark Blue
+		if isActive {
+			appNameText.Color = highlightText
 		}
 		details.Add(appNameText)
 
 		row.Add(details)
+		for _, badge := range stateBadges(item.state) {
+			row.Add(stateBadge(badge))
+		}
 
 		// Create a background for the entire row
 		var background *canvas.Rectangle
-		if isTopItem {
-			background = canvas.NewRectangle(color.NRGBA{R: 144, G: 238, B: 144, A: 255}) // Light Green
-		} else if isActive {
-			background = canvas.NewRectangle(theme.Color(theme.ColorNameSelection))
+		if isActive {
+			background = canvas.NewRectangle(highlight)
 		} else {
 			background = canvas.NewRectangle(color.Transparent)
 		}
@@ -152,14 +308,225 @@ func generatePreviewContent(items []CycleItem, currentItem *CycleItem) *fyne.Con
 		// Combine background and content
 		rowContainer := container.NewStack(background, row)
 
-		listContainer.Add(rowContainer)
+		listContainer.Add(newPreviewRow(rowContainer, listContainer, i, onDrag, onDrop))
 		listContainer.Add(layout.NewSpacer()) // Add space between items
 	}
 
 	// Wrap the list in a scroll container
 	scroll := container.NewScroll(listContainer)
-	scroll.SetMinSize(fyne.NewSize(400, 300))
+	scroll.SetMinSize(fyne.NewSize(cfg.Width-previewMargin, cfg.Height-previewMargin))
 
 	// Align the scroll container to the left
 	return container.NewHBox(scroll, layout.NewSpacer())
 }
+
+// generatePreviewGrid shows each item as a thumbnail with its title below,
+// with the item at index current highlighted.
+func generatePreviewGrid(items []CycleItem, slots []int, current int, cfg PreviewConfig, thumbs *thumbnailViews, icons *iconCache) *fyne.Container {
+	highlight, _ := parseColor(cfg.Highlight)
+	highlightText, _ := parseColor(cfg.HighlightText)
+
+	// Titles are cut to roughly what fits under the thumbnail.
+	maxTitle := max(4, int(cfg.ThumbnailWidth/8))
+
+	cellSize := fyne.NewSize(cfg.ThumbnailWidth+2*theme.Padding(), cfg.ThumbnailHeight+40)
+	grid := container.NewGridWrap(cellSize)
+	for i, item := range items {
+		title := item.title
+		if slots[i] < MaxSlots {
+			title = strconv.Itoa(slots[i]+1) + " " + title
+		}
+		if r := []rune(title); len(r) > maxTitle {
+			title = string(r[:maxTitle-1]) + "…"
+		}
+		titleText := canvas.NewText(title, theme.Color(theme.ColorNameForeground))
+		titleText.TextSize = 12
+		if item.window == 0 {
+			titleText.Color = theme.Color(theme.ColorNamePlaceHolder)
+		}
+
+		background := canvas.NewRectangle(color.Transparent)
+		if i == current {
+			background.FillColor = highlight
+			titleText.Color = highlightText
+			titleText.TextStyle = fyne.TextStyle{Bold: true}
+		}
+
+		var caption fyne.CanvasObject = titleText
+		if icons != nil {
+			caption = container.NewHBox(iconImage(icons, item, cfg), titleText)
+		}
+		cell := container.NewVBox(thumbnailImage(thumbs, item, cfg), caption)
+		grid.Add(container.NewStack(background, container.NewPadded(cell)))
+	}
+
+	scroll := container.NewScroll(grid)
+	scroll.SetMinSize(fyne.NewSize(cfg.Width-previewMargin, cfg.Height-previewMargin))
+	return container.NewHBox(scroll, layout.NewSpacer())
+}
+
+// thumbnailViews keeps one canvas.Image per window across rebuilds of the
+// preview. fyne caches a texture per image object and only uploads it again
+// when the object is refreshed, which is done when a new capture arrives.
+type thumbnailViews struct {
+	mu     sync.Mutex
+	cache  *thumbnailCache
+	images map[WindowID]*canvas.Image
+}
+
+func newThumbnailViews(cache *thumbnailCache) *thumbnailViews {
+	return &thumbnailViews{cache: cache, images: make(map[WindowID]*canvas.Image)}
+}
+
+// Get returns the image showing the latest capture of id, or nil if there
+// is none yet.
+func (v *thumbnailViews) Get(id WindowID) *canvas.Image {
+	img := v.cache.Get(id)
+	if img == nil {
+		return nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	view, ok := v.images[id]
+	if !ok {
+		view = canvas.NewImageFromImage(img)
+		view.FillMode = canvas.ImageFillContain
+		v.images[id] = view
+	} else if view.Image != img {
+		view.Image = img
+		view.Refresh()
+	}
+	return view
+}
+
+// Retain drops the images and thumbnails of windows that are not in keep.
+func (v *thumbnailViews) Retain(keep map[WindowID]bool) {
+	v.cache.Retain(keep)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for id := range v.images {
+		if !keep[id] {
+			delete(v.images, id)
+		}
+	}
+}
+
+// thumbnailImage shows the cached thumbnail for item, or an empty box of the
+// same size until one has been captured.
+func thumbnailImage(thumbs *thumbnailViews, item CycleItem, cfg PreviewConfig) fyne.CanvasObject {
+	size := fyne.NewSize(cfg.ThumbnailWidth, cfg.ThumbnailHeight)
+	if thumbs != nil && item.window != 0 {
+		if thumb := thumbs.Get(item.window); thumb != nil {
+			thumb.SetMinSize(size)
+			return thumb
+		}
+	}
+	empty := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	empty.SetMinSize(size)
+	return empty
+}
+
+// iconImage shows the icon for item, or leaves the same space empty so that
+// titles stay aligned.
+func iconImage(icons *iconCache, item CycleItem, cfg PreviewConfig) fyne.CanvasObject {
+	size := fyne.NewSize(cfg.IconSize, cfg.IconSize)
+	if res := icons.Get(item); res != nil {
+		icon := canvas.NewImageFromResource(res)
+		icon.FillMode = canvas.ImageFillContain
+		icon.SetMinSize(size)
+		return icon
+	}
+	empty := canvas.NewRectangle(color.Transparent)
+	empty.SetMinSize(size)
+	return empty
+}
+
+// previewRow makes a preview row draggable. Dropping it moves the item by
+// as many rows as it was dragged.
+type previewRow struct {
+	widget.BaseWidget
+	content  fyne.CanvasObject
+	list     *fyne.Container
+	index    int
+	dragging bool
+	onDrag   func()
+	onDrop   func(from, to int)
+}
+
+func newPreviewRow(content fyne.CanvasObject, list *fyne.Container, index int, onDrag func(), onDrop func(from, to int)) *previewRow {
+	r := &previewRow{content: content, list: list, index: index, onDrag: onDrag, onDrop: onDrop}
+	r.ExtendBaseWidget(r)
+	return r
+}
+
+func (r *previewRow) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(r.content)
+}
+
+func (r *previewRow) Dragged(ev *fyne.DragEvent) {
+	if !r.dragging {
+		r.dragging = true
+		r.onDrag()
+	}
+	r.Move(fyne.NewPos(r.Position().X, r.Position().Y+ev.Dragged.DY))
+}
+
+// DragEnd drops the row in front of the first other row whose middle is
+// below its own. The spacers between rows stretch when the ring is shorter
+// than the preview, so the rows' positions are compared rather than
+// assuming a fixed row pitch.
+func (r *previewRow) DragEnd() {
+	r.dragging = false
+	middle := r.Position().Y + r.Size().Height/2
+	to := 0
+	for _, obj := range r.list.Objects {
+		other, ok := obj.(*previewRow)
+		if !ok || other == r {
+			continue
+		}
+		if other.Position().Y+other.Size().Height/2 < middle {
+			to++
+		}
+	}
+	r.onDrop(r.index, to)
+}
+
+// stateBadge draws a window state label as a small rounded tag.
+func stateBadge(label string) fyne.CanvasObject {
+	bg := theme.Color(theme.ColorNameInputBackground)
+	if label == "attention" {
+		bg = theme.Color(theme.ColorNameWarning)
+	}
+	rect := canvas.NewRectangle(bg)
+	rect.CornerRadius = 4
+	text := canvas.NewText(" "+label+" ", theme.Color(theme.ColorNameForeground))
+	text.TextSize = 11
+	return container.NewCenter(container.NewStack(rect, text))
+}
+
+// centerWindow moves w to the middle of the monitor showing target, when
+// the backend knows the monitor layout. It must be called after w is shown,
+// as the native window doesn't exist before, and not from the main thread.
+func centerWindow(w fyne.Window, backend WindowBackend, target WindowID) {
+	ms, ok := backend.(MonitorSource)
+	if !ok || target == 0 {
+		return
+	}
+	nw, ok := w.(driver.NativeWindow)
+	if !ok {
+		return
+	}
+	var handle WindowID
+	nw.RunNative(func(ctx any) {
+		if x11, ok := ctx.(driver.X11WindowContext); ok {
+			handle = WindowID(x11.WindowHandle)
+		}
+	})
+	if handle == 0 {
+		return
+	}
+	if err := centerOn(ms, handle, target); err != nil {
+		log.Printf("Failed to center window: %v\n", err)
+	}
+}
@@ -0,0 +1,196 @@
+package cycle
+
+import (
+	"log"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+)
+
+// filterItems returns the items whose title or application name contains
+// filter, ignoring case, along with their slots in the ring.
+func filterItems(items []CycleItem, filter string) ([]CycleItem, []int) {
+	filter = strings.ToLower(filter)
+	var filtered []CycleItem
+	var slots []int
+	for i, item := range items {
+		if filter == "" ||
+			strings.Contains(strings.ToLower(item.title), filter) ||
+			strings.Contains(strings.ToLower(item.appName), filter) {
+			filtered = append(filtered, item)
+			slots = append(slots, i)
+		}
+	}
+	return filtered, slots
+}
+
+// view returns the active ring's items as the preview shows them: filtered,
+// with their ring slots and the index of the highlighted one.
+func (p *Preview) view() (*CycleList, []CycleItem, []int, int) {
+	p.mu.Lock()
+	filter, selected := p.filter, p.selected
+	p.mu.Unlock()
+
+	active := p.rings.Active()
+	all, current := active.GetItems()
+	items, slots := filterItems(all, filter)
+	if selected >= 0 {
+		return active, items, slots, min(selected, len(items)-1)
+	}
+	for i, slot := range slots {
+		if slot == current {
+			return active, items, slots, i
+		}
+	}
+	return active, items, slots, -1
+}
+
+// addModifiedShortcuts lets the preview be driven while the cycle modifiers
+// are still held. The driver reports keys pressed with Alt, Control or Super
+// as shortcuts instead of typed keys and drops the runes they would type,
+// so every navigation key and every letter and digit is registered as a
+// shortcut under each of those modifiers, with and without Shift.
+func (p *Preview) addModifiedShortcuts(c fyne.Canvas) {
+	nav := []fyne.KeyName{
+		fyne.KeyUp, fyne.KeyDown, fyne.KeyHome, fyne.KeyEnd,
+		fyne.KeyBackspace, fyne.KeyReturn, fyne.KeyEnter, fyne.KeyEscape,
+	}
+	runes := []rune("abcdefghijklmnopqrstuvwxyz0123456789")
+
+	for _, m := range []fyne.KeyModifier{fyne.KeyModifierAlt, fyne.KeyModifierControl, fyne.KeyModifierSuper} {
+		for _, mod := range []fyne.KeyModifier{m, m | fyne.KeyModifierShift} {
+			for _, key := range nav {
+				key := key
+				c.AddShortcut(&desktop.CustomShortcut{KeyName: key, Modifier: mod}, func(fyne.Shortcut) {
+					p.typedKey(&fyne.KeyEvent{Name: key})
+				})
+			}
+			for _, r := range runes {
+				r := r
+				key := fyne.KeyName(strings.ToUpper(string(r)))
+				c.AddShortcut(&desktop.CustomShortcut{KeyName: key, Modifier: mod}, func(fyne.Shortcut) {
+					p.typedRune(r)
+				})
+			}
+		}
+	}
+}
+
+// Navigating reports whether the keyboard has been used to pick an item,
+// in which case releasing the cycle modifiers leaves the preview open until
+// Enter or Escape.
+func (p *Preview) Navigating() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.visible && (p.selected >= 0 || p.filter != "")
+}
+
+// typedKey handles navigation while the preview has focus. Arrow keys,
+// Home and End move the selection without focusing anything; Enter focuses
+// the selection and Escape goes back to the window that was active before.
+func (p *Preview) typedKey(ev *fyne.KeyEvent) {
+	_, items, _, current := p.view()
+
+	p.mu.Lock()
+	switch ev.Name {
+	case fyne.KeyUp, fyne.KeyDown:
+		if len(items) > 0 {
+			step := 1
+			if ev.Name == fyne.KeyUp {
+				step = -1
+			}
+			if current < 0 {
+				current = 0
+				step = 0
+			}
+			p.selected = (current + step + len(items)) % len(items)
+		}
+	case fyne.KeyHome:
+		p.selected = 0
+	case fyne.KeyEnd:
+		p.selected = max(len(items)-1, 0)
+	case fyne.KeyBackspace:
+		if r := []rune(p.filter); len(r) > 0 {
+			p.filter = string(r[:len(r)-1])
+			p.selected = 0
+		}
+	case fyne.KeyReturn, fyne.KeyEnter:
+		p.mu.Unlock()
+		p.CommitSelection()
+		p.rings.Active().EndCycle()
+		p.HidePreview()
+		return
+	case fyne.KeyEscape:
+		p.mu.Unlock()
+		p.cancel()
+		return
+	default:
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	p.updateContent()
+}
+
+// typedRune narrows the list to items matching what has been typed.
+func (p *Preview) typedRune(r rune) {
+	p.mu.Lock()
+	p.filter += string(r)
+	p.selected = 0
+	p.mu.Unlock()
+	p.updateContent()
+}
+
+// CommitSelection focuses the item picked with the keyboard, if any. It is
+// called when the cycle modifiers are released and on Enter.
+func (p *Preview) CommitSelection() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	picked := p.selected >= 0 || p.filter != ""
+	p.mu.Unlock()
+	if !picked {
+		return
+	}
+
+	active, items, slots, current := p.view()
+	if current < 0 || current >= len(items) {
+		log.Println("Nothing selected in the preview")
+		return
+	}
+	if err := active.FocusSlot(slots[current]); err != nil {
+		log.Printf("Failed to focus the selection: %v\n", err)
+	}
+
+	p.mu.Lock()
+	p.filter = ""
+	p.selected = -1
+	p.mu.Unlock()
+}
+
+// cancel hides the preview and focuses the window that was active before
+// the cycle started.
+func (p *Preview) cancel() {
+	p.mu.Lock()
+	origin := p.origin
+	p.mu.Unlock()
+
+	p.HidePreview()
+	if origin == 0 {
+		return
+	}
+	if err := p.rings.backend.Focus(origin); err != nil {
+		log.Printf("Failed to return to window %s: %v\n", origin, err)
+		return
+	}
+	// Make the original window current again so that ending the cycle
+	// doesn't promote the one that was cycled to.
+	active := p.rings.Active()
+	active.setActiveWindow(origin)
+	active.EndCycle()
+}
@@ -0,0 +1,334 @@
+package cycle
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rings holds every named CycleList and tracks which one is active, i.e.
+// the one whose hotkey was used last. It also owns the window monitor so
+// that a single event subscription feeds all rings.
+type Rings struct {
+	mu           sync.Mutex
+	backend      WindowBackend
+	rings        []*CycleList
+	active       *CycleList
+	stopChan     chan struct{}
+	pollInterval time.Duration
+	rules        []*Rule
+	// known is every window seen in the last listing, so that rules only
+	// apply to windows that appear afterwards. Nil until the first listing.
+	known       map[WindowID]bool
+	subscribers []func(RingEvent)
+}
+
+func NewRings(backend WindowBackend, cfg Config) *Rings {
+	r := &Rings{
+		backend:      backend,
+		stopChan:     make(chan struct{}),
+		pollInterval: cfg.Timing.ActiveWindowPoll,
+	}
+	r.rings = append(r.rings, NewCycleList(DefaultRingName, backend, cfg))
+	for _, rc := range cfg.Rings {
+		r.rings = append(r.rings, NewCycleList(rc.Name, backend, cfg))
+	}
+	r.active = r.rings[0]
+	for _, cl := range r.rings {
+		cl.notify = r.emit
+	}
+
+	rules, errs := compileRules(cfg.Rules)
+	for _, err := range errs {
+		log.Printf("Ignoring %v\n", err)
+	}
+	r.rules = rules
+	return r
+}
+
+// All returns the rings in config order, default first.
+func (r *Rings) All() []*CycleList {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*CycleList(nil), r.rings...)
+}
+
+func (r *Rings) Get(name string) *CycleList {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cl := range r.rings {
+		if cl.name == name {
+			return cl
+		}
+	}
+	return nil
+}
+
+func (r *Rings) Active() *CycleList {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active
+}
+
+// Subscribe calls fn for every change to any ring. fn runs while the ring
+// is locked, so it must return quickly and must not call back into it.
+func (r *Rings) Subscribe(fn func(RingEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// eventQueueSize is how many events may wait for a SubscribeQueue consumer
+// before new ones are dropped.
+const eventQueueSize = 64
+
+// SubscribeQueue returns a channel of the events for which keep returns
+// true, or of every event if keep is nil, for a consumer on a goroutine of
+// its own. Subscribers run with a ring locked, so when the consumer falls
+// behind events are dropped rather than waited for; name says whose in the
+// log.
+func (r *Rings) SubscribeQueue(name string, keep func(RingEvent) bool) <-chan RingEvent {
+	events := make(chan RingEvent, eventQueueSize)
+	r.Subscribe(queueEvents(name, events, keep))
+	return events
+}
+
+func queueEvents(name string, events chan<- RingEvent, keep func(RingEvent) bool) func(RingEvent) {
+	return func(ev RingEvent) {
+		if keep != nil && !keep(ev) {
+			return
+		}
+		select {
+		case events <- ev:
+		default:
+			log.Printf("%s are falling behind, dropping event for %s", name, ev.Title)
+		}
+	}
+}
+
+func (r *Rings) emit(ev RingEvent) {
+	r.mu.Lock()
+	subscribers := r.subscribers
+	r.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(ev)
+	}
+}
+
+func (r *Rings) SetActive(cl *CycleList) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.active != cl {
+		log.Printf("Active ring is now %s\n", cl.name)
+	}
+	r.active = cl
+}
+
+// MoveActiveWindow puts the focused window into the target ring and takes it
+// out of all others.
+func (r *Rings) MoveActiveWindow(target *CycleList) {
+	win, err := r.backend.ActiveWindow()
+	if err != nil {
+		log.Printf("Failed to get active window: %v\n", err)
+		return
+	}
+	r.MoveWindow(win, target)
+}
+
+func (r *Rings) MoveWindow(win WindowInfo, target *CycleList) {
+	for _, cl := range r.All() {
+		if cl != target {
+			cl.RemoveWindow(win.ID)
+		}
+	}
+	target.AddWindow(win)
+	log.Printf("Moved window %s (Window ID: %s) to ring %s\n", win.Title, win.ID, target.name)
+}
+
+// Restore restores every ring. The default ring uses path; the others use
+// sibling files named after the ring.
+func (r *Rings) Restore(path string) error {
+	var errs []string
+	for _, cl := range r.All() {
+		if err := cl.Restore(NewStore(ringStorePath(path, cl.name))); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Flush writes every ring change that is still waiting to be saved.
+func (r *Rings) Flush() {
+	for _, cl := range r.All() {
+		cl.flush()
+	}
+}
+
+func ringStorePath(path, ring string) string {
+	if ring == DefaultRingName {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + ring + ext
+}
+
+// MonitorActiveWindow keeps each ring's current item in step with the
+// focused window until StopMonitor is called. Backends that implement
+// WindowEventSource are followed event by event; all others are polled.
+func (r *Rings) MonitorActiveWindow() {
+	stop := r.monitorStop()
+	if stop == nil {
+		return
+	}
+
+	if source, ok := r.backend.(WindowEventSource); ok {
+		events, err := source.WatchWindows(stop)
+		if err == nil {
+			r.watchActiveWindow(events, stop)
+			return
+		}
+		log.Printf("Failed to watch window events, falling back to polling: %v\n", err)
+	}
+
+	r.pollActiveWindow(stop)
+}
+
+// Save writes every ring to its store.
+func (r *Rings) Save() error {
+	var errs []string
+	for _, cl := range r.All() {
+		if err := cl.Save(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (r *Rings) watchActiveWindow(events <-chan WindowEvent, stop <-chan struct{}) {
+	r.syncActiveWindow()
+	r.windowsChanged()
+
+	for {
+		select {
+		case <-stop:
+			log.Println("Active window monitor stopped")
+			return
+		case ev, ok := <-events:
+			if !ok {
+				log.Println("Window events ended, falling back to polling")
+				r.pollActiveWindow(stop)
+				return
+			}
+			switch ev {
+			case ActiveWindowChanged:
+				r.syncActiveWindow()
+			case ClientListChanged:
+				r.windowsChanged()
+			}
+		}
+	}
+}
+
+func (r *Rings) pollActiveWindow(stop <-chan struct{}) {
+	for {
+		delay := r.pollInterval
+		if err := r.syncActiveWindow(); err != nil {
+			delay = 1 * time.Second
+		}
+		r.windowsChanged()
+
+		select {
+		case <-stop:
+			log.Println("Active window monitor stopped")
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (r *Rings) syncActiveWindow() error {
+	win, err := r.backend.ActiveWindow()
+	if err != nil {
+		return err
+	}
+	// The preview and switcher hold focus only while they are open.
+	if win.PID == os.Getpid() {
+		return nil
+	}
+	for _, cl := range r.All() {
+		cl.setActiveWindow(win.ID)
+	}
+	return nil
+}
+
+// windowsChanged prunes closed windows, attaches placeholders and applies
+// add rules to new windows in every ring from a single window listing.
+func (r *Rings) windowsChanged() {
+	windows, err := r.backend.ListWindows()
+	if err != nil {
+		log.Printf("Failed to list windows: %v\n", err)
+		return
+	}
+	open := openSet(windows)
+
+	r.mu.Lock()
+	known := r.known
+	r.known = open
+	r.mu.Unlock()
+
+	for _, cl := range r.All() {
+		cl.pruneWindows(open)
+		cl.attachWindows(windows, known)
+	}
+	if known == nil {
+		return
+	}
+	for _, w := range windows {
+		if !known[w.ID] {
+			r.applyRules(w)
+		}
+	}
+}
+
+// applyRules adds a newly opened window to the ring of every matching rule.
+func (r *Rings) applyRules(w WindowInfo) {
+	for _, rule := range r.rules {
+		if !rule.Matches(w) {
+			continue
+		}
+		cl := r.Get(rule.target())
+		if cl == nil {
+			continue
+		}
+		if cl.AddWindow(w) {
+			log.Printf("Rule added %s (Window ID: %s) to ring %s\n", w.Title, w.ID, cl.name)
+		}
+	}
+}
+
+func (r *Rings) monitorStop() chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopChan
+}
+
+// StopMonitor ends MonitorActiveWindow. It is safe to call more than once.
+func (r *Rings) StopMonitor() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stopChan != nil {
+		close(r.stopChan)
+		r.stopChan = nil
+	}
+}
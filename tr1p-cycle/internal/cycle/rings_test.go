@@ -0,0 +1,51 @@
+package cycle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueEvents(t *testing.T) {
+	events := make(chan RingEvent, 1)
+	queue := queueEvents("Tests", events, func(ev RingEvent) bool { return ev.Kind == ItemAdded })
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			queue(RingEvent{Kind: ItemAdded, Window: WindowID(i)})
+		}
+		queue(RingEvent{Kind: ItemFocused})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("queueEvents blocked on a full queue")
+	}
+	if len(events) != 1 {
+		t.Fatalf("queued %d events, want 1", len(events))
+	}
+	if ev := <-events; ev.Window != 0 {
+		t.Errorf("kept event for window %s, want the first", ev.Window)
+	}
+}
+
+func TestSubscribeQueue(t *testing.T) {
+	backend := NewFakeBackend(WindowInfo{ID: 1, Title: "a"})
+	rings := NewRings(backend, DefaultConfig())
+	all := rings.SubscribeQueue("Tests", nil)
+	focused := rings.SubscribeQueue("Tests", func(ev RingEvent) bool { return ev.Kind == ItemFocused })
+
+	cl := rings.Get(DefaultRingName)
+	cl.Add("")
+	if err := cl.FocusSlot(0); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) < 2 {
+		t.Errorf("unfiltered queue holds %d events, want every one", len(all))
+	}
+	if len(focused) != 1 || (<-focused).Kind != ItemFocused {
+		t.Error("filtered queue does not hold just the focus event")
+	}
+}
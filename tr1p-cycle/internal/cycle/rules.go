@@ -0,0 +1,99 @@
+package cycle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RuleConfig matches windows. Every field that is set must match; at least
+// one must be set. In [[rules]] a match adds newly opened windows to Ring,
+// or to the default ring when Ring is empty. In [[exclude]] a match stops
+// windows from being added to Ring, or to any ring when Ring is empty.
+type RuleConfig struct {
+	Ring      string `toml:"ring"`
+	Class     string `toml:"class"`
+	AppName   string `toml:"app_name"`
+	Title     string `toml:"title"` // regular expression
+	Workspace *int   `toml:"workspace"`
+	Type      string `toml:"type"` // _NET_WM_WINDOW_TYPE, e.g. "dock"
+}
+
+type Rule struct {
+	ring       string
+	class      string
+	appName    string
+	title      *regexp.Regexp
+	workspace  *int
+	windowType string
+}
+
+func compileRule(rc RuleConfig) (*Rule, error) {
+	if rc.Class == "" && rc.AppName == "" && rc.Title == "" && rc.Workspace == nil && rc.Type == "" {
+		return nil, fmt.Errorf("rule matches every window; set class, app_name, title, workspace or type")
+	}
+
+	r := &Rule{
+		ring:       rc.Ring,
+		class:      rc.Class,
+		appName:    rc.AppName,
+		workspace:  rc.Workspace,
+		windowType: strings.ToLower(rc.Type),
+	}
+	if rc.Title != "" {
+		re, err := regexp.Compile(rc.Title)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title pattern: %v", err)
+		}
+		r.title = re
+	}
+	return r, nil
+}
+
+// compileRules compiles every rule, skipping and reporting the ones that
+// are invalid.
+func compileRules(rcs []RuleConfig) ([]*Rule, []error) {
+	var rules []*Rule
+	var errs []error
+	for i, rc := range rcs {
+		r, err := compileRule(rc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %v", i+1, err))
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules, errs
+}
+
+func (r *Rule) Matches(w WindowInfo) bool {
+	if r.class != "" && !strings.EqualFold(r.class, w.Class) {
+		return false
+	}
+	if r.appName != "" && r.appName != w.AppName {
+		return false
+	}
+	if r.title != nil && !r.title.MatchString(w.Title) {
+		return false
+	}
+	if r.workspace != nil && *r.workspace != w.Desktop {
+		return false
+	}
+	if r.windowType != "" && r.windowType != w.Type {
+		return false
+	}
+	return true
+}
+
+// appliesTo reports whether an exclude rule covers the named ring.
+func (r *Rule) appliesTo(ring string) bool {
+	return r.ring == "" || r.ring == ring
+}
+
+// target returns the ring an add rule puts windows into.
+func (r *Rule) target() string {
+	if r.ring == "" {
+		return DefaultRingName
+	}
+	return r.ring
+}
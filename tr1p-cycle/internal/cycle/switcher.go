@@ -0,0 +1,205 @@
+package cycle
+
+import (
+	"log"
+	"os"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Switcher is a search over every window the window manager lists, for
+// reaching windows that aren't in any ring. Enter focuses the selected
+// window and Ctrl+Enter also adds it to the active ring.
+type Switcher struct {
+	window   fyne.Window
+	entry    *switcherEntry
+	list     *widget.List
+	rings    *Rings
+	mu       sync.Mutex
+	windows  []WindowInfo
+	results  []WindowInfo
+	selected int
+}
+
+func NewSwitcher(app fyne.App, rings *Rings, cfg PreviewConfig) *Switcher {
+	drv, ok := app.Driver().(desktop.Driver)
+	if !ok {
+		log.Println("Failed to create Switcher: driver does not support desktop")
+		return nil
+	}
+
+	s := &Switcher{rings: rings}
+	s.entry = newSwitcherEntry(s)
+	s.entry.SetPlaceHolder("Search windows")
+	s.entry.OnChanged = s.search
+
+	s.list = widget.NewList(
+		func() int {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return len(s.results)
+		},
+		func() fyne.CanvasObject {
+			title := canvas.NewText("", theme.Color(theme.ColorNameForeground))
+			title.TextSize = 16
+			appName := canvas.NewText("", theme.Color(theme.ColorNamePlaceHolder))
+			appName.TextSize = 12
+			return container.NewVBox(title, appName)
+		},
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if id >= len(s.results) {
+				return
+			}
+			texts := obj.(*fyne.Container).Objects
+			texts[0].(*canvas.Text).Text = s.results[id].Title
+			texts[1].(*canvas.Text).Text = s.results[id].AppName
+			texts[0].Refresh()
+			texts[1].Refresh()
+		},
+	)
+	s.list.OnSelected = func(id widget.ListItemID) {
+		s.mu.Lock()
+		s.selected = id
+		s.mu.Unlock()
+	}
+
+	w := drv.CreateSplashWindow()
+	w.SetContent(container.NewBorder(container.NewPadded(s.entry), nil, nil, nil, s.list))
+	w.Resize(fyne.NewSize(cfg.Width, cfg.Height))
+	s.window = w
+	return s
+}
+
+// Show lists the open windows and opens the search.
+func (s *Switcher) Show() {
+	if s == nil {
+		return
+	}
+	windows, err := s.rings.backend.ListWindows()
+	if err != nil {
+		log.Printf("Failed to list windows: %v\n", err)
+		return
+	}
+	// Leave out our own windows, like the preview and the switcher itself.
+	own := os.Getpid()
+	s.mu.Lock()
+	s.windows = s.windows[:0]
+	for _, w := range windows {
+		if w.PID != own {
+			s.windows = append(s.windows, w)
+		}
+	}
+	s.mu.Unlock()
+
+	active, err := s.rings.backend.ActiveWindow()
+	if err != nil {
+		log.Printf("Failed to get active window: %v\n", err)
+	}
+
+	s.entry.SetText("")
+	s.search("")
+	s.window.Show()
+	centerWindow(s.window, s.rings.backend, active.ID)
+	s.window.RequestFocus()
+	s.window.Canvas().Focus(s.entry)
+}
+
+func (s *Switcher) Hide() {
+	if s == nil {
+		return
+	}
+	s.window.Hide()
+}
+
+func (s *Switcher) search(query string) {
+	s.mu.Lock()
+	s.results = rankWindows(s.windows, query)
+	s.selected = 0
+	s.mu.Unlock()
+
+	s.list.Refresh()
+	s.list.Select(0)
+	s.list.ScrollToTop()
+}
+
+// move shifts the selection by delta, wrapping around.
+func (s *Switcher) move(delta int) {
+	s.mu.Lock()
+	n := len(s.results)
+	if n == 0 {
+		s.mu.Unlock()
+		return
+	}
+	id := ((s.selected+delta)%n + n) % n
+	s.mu.Unlock()
+
+	s.list.Select(id)
+	s.list.ScrollTo(id)
+}
+
+// activate focuses the selected window and, if add is set, adds it to the
+// active ring.
+func (s *Switcher) activate(add bool) {
+	s.mu.Lock()
+	if s.selected < 0 || s.selected >= len(s.results) {
+		s.mu.Unlock()
+		return
+	}
+	win := s.results[s.selected]
+	s.mu.Unlock()
+
+	s.Hide()
+	if add {
+		s.rings.Active().AddWindow(win)
+	}
+	if err := s.rings.backend.Focus(win.ID); err != nil {
+		log.Printf("Error focusing window: %s\n", err)
+	}
+}
+
+// switcherEntry is the search field. It passes the keys that drive the
+// result list to the Switcher instead of editing the text.
+type switcherEntry struct {
+	widget.Entry
+	s *Switcher
+}
+
+func newSwitcherEntry(s *Switcher) *switcherEntry {
+	e := &switcherEntry{s: s}
+	e.ExtendBaseWidget(e)
+	return e
+}
+
+func (e *switcherEntry) TypedKey(ev *fyne.KeyEvent) {
+	switch ev.Name {
+	case fyne.KeyUp:
+		e.s.move(-1)
+	case fyne.KeyDown:
+		e.s.move(1)
+	case fyne.KeyReturn, fyne.KeyEnter:
+		e.s.activate(false)
+	case fyne.KeyEscape:
+		e.s.Hide()
+	default:
+		e.Entry.TypedKey(ev)
+	}
+}
+
+// TypedShortcut catches Ctrl+Enter, which the driver reports as a shortcut
+// rather than a key.
+func (e *switcherEntry) TypedShortcut(sc fyne.Shortcut) {
+	if cs, ok := sc.(*desktop.CustomShortcut); ok && cs.Modifier == fyne.KeyModifierControl &&
+		(cs.KeyName == fyne.KeyReturn || cs.KeyName == fyne.KeyEnter) {
+		e.s.activate(true)
+		return
+	}
+	e.Entry.TypedShortcut(sc)
+}
@@ -0,0 +1,163 @@
+package cycle
+
+import (
+	"image"
+	"log"
+	"sync"
+	"time"
+)
+
+type thumbnail struct {
+	img   image.Image
+	taken time.Time
+}
+
+// thumbnailCache captures windows in the background so the preview never
+// waits on the X server. Get returns whatever is cached and queues a capture
+// when that is missing or older than refresh; onUpdate runs once the queue
+// has been worked through.
+type thumbnailCache struct {
+	mu       sync.Mutex
+	capturer WindowCapturer
+	width    int
+	height   int
+	refresh  time.Duration
+	images   map[WindowID]thumbnail
+	queue    []WindowID
+	queued   map[WindowID]bool
+	// captured holds the windows captured since they were last released.
+	captured map[WindowID]bool
+	running  bool
+	onUpdate func()
+}
+
+func newThumbnailCache(capturer WindowCapturer, width, height int, refresh time.Duration, onUpdate func()) *thumbnailCache {
+	return &thumbnailCache{
+		capturer: capturer,
+		width:    width,
+		height:   height,
+		refresh:  refresh,
+		images:   make(map[WindowID]thumbnail),
+		queued:   make(map[WindowID]bool),
+		captured: make(map[WindowID]bool),
+		onUpdate: onUpdate,
+	}
+}
+
+// Get returns the cached thumbnail for id, or nil if there is none yet.
+func (t *thumbnailCache) Get(id WindowID) image.Image {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	th, ok := t.images[id]
+	if (!ok || time.Since(th.taken) >= t.refresh) && !t.queued[id] {
+		t.queued[id] = true
+		t.queue = append(t.queue, id)
+		if !t.running {
+			t.running = true
+			go t.run()
+		}
+	}
+	return th.img
+}
+
+// Retain drops thumbnails of windows that are not in keep and releases
+// them.
+func (t *thumbnailCache) Retain(keep map[WindowID]bool) {
+	t.mu.Lock()
+	for id := range t.images {
+		if !keep[id] {
+			delete(t.images, id)
+		}
+	}
+	release := t.takeCaptured(keep)
+	t.mu.Unlock()
+
+	for _, id := range release {
+		t.capturer.ReleaseWindow(id)
+	}
+}
+
+// Pause releases every captured window while no thumbnails are shown. The
+// images are kept, so they show again straight away until recaptured.
+func (t *thumbnailCache) Pause() {
+	t.mu.Lock()
+	release := t.takeCaptured(nil)
+	t.mu.Unlock()
+
+	for _, id := range release {
+		t.capturer.ReleaseWindow(id)
+	}
+}
+
+// takeCaptured forgets the captured windows that are not in keep and
+// returns them. Callers must hold t.mu.
+func (t *thumbnailCache) takeCaptured(keep map[WindowID]bool) []WindowID {
+	var ids []WindowID
+	for id := range t.captured {
+		if !keep[id] {
+			delete(t.captured, id)
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (t *thumbnailCache) run() {
+	updated := false
+	for {
+		t.mu.Lock()
+		if len(t.queue) == 0 {
+			t.running = false
+			t.mu.Unlock()
+			break
+		}
+		id := t.queue[0]
+		t.queue = t.queue[1:]
+		t.mu.Unlock()
+
+		img, err := t.capturer.CaptureWindow(id, t.width, t.height)
+
+		t.mu.Lock()
+		delete(t.queued, id)
+		t.captured[id] = true
+		if err != nil {
+			// Keep the old image and don't retry until the next refresh.
+			log.Printf("Failed to capture window %s: %v\n", id, err)
+			t.images[id] = thumbnail{img: t.images[id].img, taken: time.Now()}
+		} else {
+			t.images[id] = thumbnail{img: img, taken: time.Now()}
+			updated = true
+		}
+		t.mu.Unlock()
+	}
+
+	if updated {
+		t.onUpdate()
+	}
+}
+
+// fitImage scales src down, keeping its aspect ratio, so that it fits within
+// maxWidth x maxHeight. Nearest-neighbour sampling is plenty for thumbnails.
+func fitImage(src image.Image, maxWidth, maxHeight int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 || (w <= maxWidth && h <= maxHeight) {
+		return src
+	}
+
+	scale := float64(maxWidth) / float64(w)
+	if s := float64(maxHeight) / float64(h); s < scale {
+		scale = s
+	}
+	dw, dh := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy := b.Min.Y + y*h/dh
+		for x := 0; x < dw; x++ {
+			dst.Set(x, y, src.At(b.Min.X+x*w/dw, sy))
+		}
+	}
+	return dst
+}
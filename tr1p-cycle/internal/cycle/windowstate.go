@@ -0,0 +1,28 @@
+package cycle
+
+// keepUnminimized drops minimized windows from open. Called with c.mu held.
+func (c *CycleList) keepUnminimized(open map[WindowID]bool) {
+	for id, item := range c.track {
+		if item.state.Has(StateHidden) {
+			delete(open, id)
+		}
+	}
+}
+
+// stateBadges returns the labels the preview shows for state, in a fixed
+// order.
+func stateBadges(state WindowState) []string {
+	var badges []string
+	if state.Has(StateDemandsAttention) {
+		badges = append(badges, "attention")
+	}
+	if state.Has(StateHidden) {
+		badges = append(badges, "minimized")
+	}
+	if state.Has(StateFullscreen) {
+		badges = append(badges, "fullscreen")
+	} else if state.Has(StateMaximized) {
+		badges = append(badges, "maximized")
+	}
+	return badges
+}
@@ -0,0 +1,66 @@
+package cycle
+
+import "log"
+
+// WorkspaceSource is implemented by backends that know which workspace is
+// shown and can move between workspaces. Without it windows are focused
+// wherever they are and the workspace setting is ignored.
+type WorkspaceSource interface {
+	CurrentDesktop() (int, error)
+	SwitchDesktop(desktop int) error
+	MoveToDesktop(id WindowID, desktop int) error
+}
+
+// keepCurrentDesktop drops windows on other workspaces from open. Sticky
+// windows are on every workspace and stay. Called with c.mu held.
+func (c *CycleList) keepCurrentDesktop(open map[WindowID]bool) {
+	ws, ok := c.backend.(WorkspaceSource)
+	if !ok {
+		return
+	}
+	current, err := ws.CurrentDesktop()
+	if err != nil {
+		log.Printf("Failed to get current workspace: %v\n", err)
+		return
+	}
+	for id, item := range c.track {
+		if item.desktop != AllDesktops && item.desktop != current {
+			delete(open, id)
+		}
+	}
+}
+
+// focusItem focuses item's window, first bringing it to the current
+// workspace or switching to its own, depending on the workspace setting,
+// and restoring it if it is minimized. Slot hotkeys and the preview reach
+// windows on other workspaces even when cycling is limited to the current
+// one, so that mode switches too; likewise they restore minimized windows
+// that cycling skips. Called with c.mu held.
+func (c *CycleList) focusItem(item *CycleItem) error {
+	ws, ok := c.backend.(WorkspaceSource)
+	if ok && item.desktop != AllDesktops {
+		if current, err := ws.CurrentDesktop(); err != nil {
+			log.Printf("Failed to get current workspace: %v\n", err)
+		} else if current != item.desktop {
+			if c.workspace == WorkspaceBring {
+				if err := ws.MoveToDesktop(item.window, current); err != nil {
+					log.Printf("Failed to bring %s to workspace %d: %v\n", item.title, current+1, err)
+				} else {
+					item.desktop = current
+				}
+			} else if err := ws.SwitchDesktop(item.desktop); err != nil {
+				log.Printf("Failed to switch to workspace %d: %v\n", item.desktop+1, err)
+			}
+		}
+	}
+	if item.state.Has(StateHidden) {
+		if r, ok := c.backend.(WindowRestorer); ok {
+			if err := r.Restore(item.window); err != nil {
+				log.Printf("Failed to restore %s: %v\n", item.title, err)
+			} else {
+				item.state &^= StateHidden
+			}
+		}
+	}
+	return c.backend.Focus(item.window)
+}
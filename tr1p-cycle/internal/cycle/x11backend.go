@@ -0,0 +1,597 @@
+package cycle
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/composite"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+// X11Backend reads EWMH properties and sends EWMH client messages over a
+// native X connection, so no helper processes are spawned.
+type X11Backend struct {
+	X           *xgb.Conn
+	root        xproto.Window
+	atoms       map[string]xproto.Atom
+	windowTypes map[xproto.Atom]string
+
+	// composite is set when the server has Composite 0.2 or later, which
+	// lets CaptureWindow read windows that are covered by others.
+	composite  bool
+	mu         sync.Mutex
+	redirected map[xproto.Window]bool
+
+	// randr is set when the server has RandR 1.3 or later, which Monitors
+	// uses to find the outputs. Without it the whole screen is one monitor.
+	randr bool
+}
+
+var x11AtomNames = []string{
+	"_NET_ACTIVE_WINDOW",
+	"_NET_CLIENT_LIST",
+	"_NET_WM_PID",
+	"_NET_WM_NAME",
+	"_NET_WM_DESKTOP",
+	"_NET_CURRENT_DESKTOP",
+	"_NET_WM_WINDOW_TYPE",
+	"_NET_WM_STATE",
+	"_NET_WM_STATE_HIDDEN",
+	"_NET_WM_STATE_FULLSCREEN",
+	"_NET_WM_STATE_MAXIMIZED_VERT",
+	"_NET_WM_STATE_MAXIMIZED_HORZ",
+	"_NET_WM_STATE_DEMANDS_ATTENTION",
+	"_NET_WM_ICON",
+	"UTF8_STRING",
+}
+
+var x11WindowTypes = []string{
+	"desktop", "dock", "toolbar", "menu", "utility", "splash", "dialog", "normal",
+}
+
+func NewX11Backend() (*X11Backend, error) {
+	X, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %v", err)
+	}
+
+	b := &X11Backend{
+		X:           X,
+		root:        xproto.Setup(X).DefaultScreen(X).Root,
+		atoms:       make(map[string]xproto.Atom),
+		windowTypes: make(map[xproto.Atom]string),
+		redirected:  make(map[xproto.Window]bool),
+	}
+	names := append([]string(nil), x11AtomNames...)
+	for _, t := range x11WindowTypes {
+		names = append(names, "_NET_WM_WINDOW_TYPE_"+strings.ToUpper(t))
+	}
+	for _, name := range names {
+		reply, err := xproto.InternAtom(X, false, uint16(len(name)), name).Reply()
+		if err != nil {
+			X.Close()
+			return nil, fmt.Errorf("failed to intern atom %s: %v", name, err)
+		}
+		b.atoms[name] = reply.Atom
+	}
+	for _, t := range x11WindowTypes {
+		b.windowTypes[b.atoms["_NET_WM_WINDOW_TYPE_"+strings.ToUpper(t)]] = t
+	}
+
+	if err := composite.Init(X); err == nil {
+		v, err := composite.QueryVersion(X, 0, 2).Reply()
+		b.composite = err == nil && (v.MajorVersion > 0 || v.MinorVersion >= 2)
+	}
+	if !b.composite {
+		log.Println("Composite extension not available, thumbnails only show visible windows")
+	}
+
+	if err := randr.Init(X); err == nil {
+		v, err := randr.QueryVersion(X, 1, 3).Reply()
+		b.randr = err == nil && (v.MajorVersion > 1 || v.MinorVersion >= 3)
+	}
+	if !b.randr {
+		log.Println("RandR extension not available, treating the screen as one monitor")
+	}
+
+	return b, nil
+}
+
+func (b *X11Backend) Close() {
+	b.X.Close()
+}
+
+func (b *X11Backend) ActiveWindow() (WindowInfo, error) {
+	ids, err := b.windowList(b.root, b.atoms["_NET_ACTIVE_WINDOW"])
+	if err != nil {
+		return WindowInfo{}, fmt.Errorf("could not get active window ID: %v", err)
+	}
+	if len(ids) == 0 || ids[0] == 0 {
+		return WindowInfo{}, fmt.Errorf("no active window")
+	}
+	return b.WindowInfo(ids[0])
+}
+
+func (b *X11Backend) ListWindows() ([]WindowInfo, error) {
+	ids, err := b.windowList(b.root, b.atoms["_NET_CLIENT_LIST"])
+	if err != nil {
+		return nil, fmt.Errorf("could not get client list: %v", err)
+	}
+
+	windows := make([]WindowInfo, 0, len(ids))
+	for _, id := range ids {
+		// Windows can disappear between reading the list and querying them.
+		info, err := b.WindowInfo(id)
+		if err != nil {
+			continue
+		}
+		windows = append(windows, info)
+	}
+	return windows, nil
+}
+
+func (b *X11Backend) WindowInfo(id WindowID) (WindowInfo, error) {
+	win := xproto.Window(id)
+
+	title, err := b.windowTitle(win)
+	if err != nil {
+		return WindowInfo{}, fmt.Errorf("could not get window title: %v", err)
+	}
+
+	info := WindowInfo{
+		ID:      id,
+		Title:   title,
+		AppName: "Unknown",
+		Class:   b.windowClass(win),
+		Desktop: b.windowDesktop(win),
+		Type:    b.windowType(win),
+		State:   b.windowState(win),
+	}
+	reply, err := b.property(win, b.atoms["_NET_WM_PID"])
+	if err == nil && reply.Format == 32 && len(reply.Value) >= 4 {
+		info.PID = int(xgb.Get32(reply.Value))
+		if name, err := processName(info.PID); err == nil {
+			info.AppName = name
+		}
+	}
+
+	return info, nil
+}
+
+func (b *X11Backend) Focus(id WindowID) error {
+	// Source indication 2 tells the window manager the request comes from a
+	// pager, which most window managers honor without focus-stealing checks.
+	if err := b.clientMessage(xproto.Window(id), "_NET_ACTIVE_WINDOW", 2, xproto.TimeCurrentTime); err != nil {
+		return fmt.Errorf("error focusing window: %v", err)
+	}
+	return nil
+}
+
+// Restore maps a minimized window, which ICCCM window managers take as a
+// request to return it to the normal state, and clears its hidden state
+// for those that only go by EWMH.
+func (b *X11Backend) Restore(id WindowID) error {
+	win := xproto.Window(id)
+	if err := xproto.MapWindowChecked(b.X, win).Check(); err != nil {
+		return fmt.Errorf("error restoring window: %v", err)
+	}
+	// Action 0 removes the state; source indication 2 is a pager again.
+	if err := b.clientMessage(win, "_NET_WM_STATE", 0, uint32(b.atoms["_NET_WM_STATE_HIDDEN"]), 0, 2); err != nil {
+		return fmt.Errorf("error restoring window: %v", err)
+	}
+	return nil
+}
+
+func (b *X11Backend) CurrentDesktop() (int, error) {
+	reply, err := b.property(b.root, b.atoms["_NET_CURRENT_DESKTOP"])
+	if err != nil {
+		return 0, fmt.Errorf("could not get current desktop: %v", err)
+	}
+	if reply.Format != 32 || len(reply.Value) < 4 {
+		return 0, fmt.Errorf("window manager does not set _NET_CURRENT_DESKTOP")
+	}
+	return int(xgb.Get32(reply.Value)), nil
+}
+
+func (b *X11Backend) SwitchDesktop(desktop int) error {
+	if err := b.clientMessage(b.root, "_NET_CURRENT_DESKTOP", uint32(desktop), xproto.TimeCurrentTime); err != nil {
+		return fmt.Errorf("error switching desktop: %v", err)
+	}
+	return nil
+}
+
+func (b *X11Backend) MoveToDesktop(id WindowID, desktop int) error {
+	if err := b.clientMessage(xproto.Window(id), "_NET_WM_DESKTOP", uint32(desktop), 2); err != nil {
+		return fmt.Errorf("error moving window to desktop: %v", err)
+	}
+	return nil
+}
+
+// clientMessage sends an EWMH request about win to the window manager.
+func (b *X11Backend) clientMessage(win xproto.Window, atom string, data ...uint32) error {
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: win,
+		Type:   b.atoms[atom],
+		Data:   xproto.ClientMessageDataUnionData32New(append(data, make([]uint32, 5-len(data))...)),
+	}
+	mask := uint32(xproto.EventMaskSubstructureRedirect | xproto.EventMaskSubstructureNotify)
+	return xproto.SendEventChecked(b.X, false, b.root, mask, string(ev.Bytes())).Check()
+}
+
+// WatchWindows subscribes to property changes on the root window. Events
+// are read from a connection of their own, which is closed once stop is, so
+// that the watcher neither lingers in WaitForEvent nor leaves events queued
+// on the backend's connection.
+func (b *X11Backend) WatchWindows(stop <-chan struct{}) (<-chan WindowEvent, error) {
+	X, err := xgb.NewConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to X server: %v", err)
+	}
+	err = xproto.ChangeWindowAttributesChecked(X, b.root, xproto.CwEventMask,
+		[]uint32{xproto.EventMaskPropertyChange}).Check()
+	if err != nil {
+		X.Close()
+		return nil, fmt.Errorf("failed to select root window events: %v", err)
+	}
+
+	go func() {
+		<-stop
+		X.Close()
+	}()
+
+	events := make(chan WindowEvent)
+	go func() {
+		defer close(events)
+		for {
+			ev, xerr := X.WaitForEvent()
+			if ev == nil && xerr == nil {
+				log.Println("X connection closed, no more window events")
+				return
+			}
+			if xerr != nil {
+				log.Printf("X error while watching windows: %v", xerr)
+				continue
+			}
+
+			pn, ok := ev.(xproto.PropertyNotifyEvent)
+			if !ok {
+				continue
+			}
+			var we WindowEvent
+			switch pn.Atom {
+			case b.atoms["_NET_ACTIVE_WINDOW"]:
+				we = ActiveWindowChanged
+			case b.atoms["_NET_CLIENT_LIST"]:
+				we = ClientListChanged
+			default:
+				continue
+			}
+
+			select {
+			case events <- we:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// CaptureWindow reads the window's contents with GetImage. With Composite
+// the window is redirected to an off-screen pixmap first, so windows hidden
+// behind others still capture correctly.
+func (b *X11Backend) CaptureWindow(id WindowID, maxWidth, maxHeight int) (image.Image, error) {
+	win := xproto.Window(id)
+	geom, err := xproto.GetGeometry(b.X, xproto.Drawable(win)).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("could not get window geometry: %v", err)
+	}
+
+	drawable := xproto.Drawable(win)
+	if b.composite {
+		b.redirect(win)
+		pix, err := xproto.NewPixmapId(b.X)
+		if err == nil && composite.NameWindowPixmapChecked(b.X, win, pix).Check() == nil {
+			defer xproto.FreePixmap(b.X, pix)
+			drawable = xproto.Drawable(pix)
+		}
+	}
+
+	reply, err := xproto.GetImage(b.X, xproto.ImageFormatZPixmap, drawable,
+		0, 0, geom.Width, geom.Height, (1<<32)-1).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("could not read window image: %v", err)
+	}
+
+	img, err := decodeZPixmap(reply.Data, int(geom.Width), int(geom.Height), xproto.Setup(b.X).ImageByteOrder)
+	if err != nil {
+		return nil, err
+	}
+	return fitImage(img, maxWidth, maxHeight), nil
+}
+
+// WindowIcon picks the smallest _NET_WM_ICON image that is at least size
+// pixels wide, or the largest one if they are all smaller, and scales it to
+// size.
+func (b *X11Backend) WindowIcon(id WindowID, size int) (image.Image, error) {
+	reply, err := b.property(xproto.Window(id), b.atoms["_NET_WM_ICON"])
+	if err != nil {
+		return nil, fmt.Errorf("could not get window icon: %v", err)
+	}
+	if reply.Format != 32 {
+		return nil, fmt.Errorf("window has no icon")
+	}
+
+	// The property is a list of width, height and width*height ARGB pixels.
+	data := make([]uint32, len(reply.Value)/4)
+	for i := range data {
+		data[i] = xgb.Get32(reply.Value[i*4:])
+	}
+	best, bestWidth := -1, 0
+	for i := 0; i+2 <= len(data); {
+		w, h, ok := iconSize(data, i)
+		if !ok {
+			break
+		}
+		if best < 0 || (bestWidth < size && w > bestWidth) || (w >= size && w < bestWidth) {
+			best, bestWidth = i, w
+		}
+		i += 2 + w*h
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("window has no icon")
+	}
+
+	w, h := int(data[best]), int(data[best+1])
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for j, argb := range data[best+2 : best+2+w*h] {
+		img.Pix[j*4] = uint8(argb >> 16)
+		img.Pix[j*4+1] = uint8(argb >> 8)
+		img.Pix[j*4+2] = uint8(argb)
+		img.Pix[j*4+3] = uint8(argb >> 24)
+	}
+	return fitImage(img, size, size), nil
+}
+
+// iconSize reads the size of the _NET_WM_ICON image starting at data[i] and
+// reports whether its pixels fit in data. The sizes come from the client, so
+// they are checked one at a time such that w*h can't overflow.
+func iconSize(data []uint32, i int) (w, h int, ok bool) {
+	w, h = int(data[i]), int(data[i+1])
+	left := len(data) - i - 2
+	if w <= 0 || h <= 0 || w > left || h > left || w > left/h {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// redirect asks the server to keep an off-screen copy of win. Automatic
+// redirection doesn't change what is shown and ends with our connection.
+func (b *X11Backend) redirect(win xproto.Window) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.redirected[win] {
+		return
+	}
+	if err := composite.RedirectWindowChecked(b.X, win, composite.RedirectAutomatic).Check(); err != nil {
+		log.Printf("Failed to redirect window %s: %v", WindowID(win), err)
+	}
+	b.redirected[win] = true
+}
+
+// ReleaseWindow undoes the redirection set up by CaptureWindow.
+func (b *X11Backend) ReleaseWindow(id WindowID) {
+	win := xproto.Window(id)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.redirected[win] {
+		return
+	}
+	delete(b.redirected, win)
+	// A window that has already been destroyed is no longer redirected
+	// either, so the error is of no interest.
+	composite.UnredirectWindowChecked(b.X, win, composite.RedirectAutomatic).Check()
+}
+
+// Monitors returns the area of every active CRTC, named after its first
+// output. Mirrored outputs share a CRTC and so count as one monitor.
+func (b *X11Backend) Monitors() ([]Monitor, error) {
+	if !b.randr {
+		screen := xproto.Setup(b.X).DefaultScreen(b.X)
+		return []Monitor{{Name: "screen", Rect: Rect{
+			Width: int(screen.WidthInPixels), Height: int(screen.HeightInPixels),
+		}}}, nil
+	}
+
+	res, err := randr.GetScreenResourcesCurrent(b.X, b.root).Reply()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get screen resources: %v", err)
+	}
+	var monitors []Monitor
+	for _, crtc := range res.Crtcs {
+		info, err := randr.GetCrtcInfo(b.X, crtc, res.ConfigTimestamp).Reply()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CRTC %d: %v", crtc, err)
+		}
+		if info.Width == 0 || info.Height == 0 || len(info.Outputs) == 0 {
+			continue
+		}
+		name := fmt.Sprintf("crtc-%d", crtc)
+		if out, err := randr.GetOutputInfo(b.X, info.Outputs[0], res.ConfigTimestamp).Reply(); err == nil {
+			name = string(out.Name)
+		}
+		monitors = append(monitors, Monitor{Name: name, Rect: Rect{
+			X: int(info.X), Y: int(info.Y), Width: int(info.Width), Height: int(info.Height),
+		}})
+	}
+	if len(monitors) == 0 {
+		return nil, fmt.Errorf("no active monitors")
+	}
+	return monitors, nil
+}
+
+// WindowGeometry returns the window's area relative to the root window.
+// The geometry of a reparented window is relative to its frame, so its
+// position is translated rather than read directly.
+func (b *X11Backend) WindowGeometry(id WindowID) (Rect, error) {
+	win := xproto.Window(id)
+	geom, err := xproto.GetGeometry(b.X, xproto.Drawable(win)).Reply()
+	if err != nil {
+		return Rect{}, fmt.Errorf("failed to get geometry of window %s: %v", id, err)
+	}
+	pos, err := xproto.TranslateCoordinates(b.X, win, b.root, 0, 0).Reply()
+	if err != nil {
+		return Rect{}, fmt.Errorf("failed to translate coordinates of window %s: %v", id, err)
+	}
+	return Rect{X: int(pos.DstX), Y: int(pos.DstY), Width: int(geom.Width), Height: int(geom.Height)}, nil
+}
+
+func (b *X11Backend) MoveWindow(id WindowID, x, y int) error {
+	err := xproto.ConfigureWindowChecked(b.X, xproto.Window(id),
+		xproto.ConfigWindowX|xproto.ConfigWindowY, []uint32{uint32(int32(x)), uint32(int32(y))}).Check()
+	if err != nil {
+		return fmt.Errorf("failed to move window %s: %v", id, err)
+	}
+	return nil
+}
+
+// decodeZPixmap converts 32 bits per pixel ZPixmap data, which is what
+// depth 24 and 32 visuals use, to RGBA.
+func decodeZPixmap(data []byte, width, height int, byteOrder byte) (*image.RGBA, error) {
+	if len(data) < width*height*4 {
+		return nil, fmt.Errorf("unsupported image format: %d bytes for %dx%d", len(data), width, height)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := 0; i < width*height; i++ {
+		px := data[i*4 : i*4+4]
+		if byteOrder == xproto.ImageOrderLSBFirst {
+			img.Pix[i*4], img.Pix[i*4+1], img.Pix[i*4+2] = px[2], px[1], px[0]
+		} else {
+			img.Pix[i*4], img.Pix[i*4+1], img.Pix[i*4+2] = px[1], px[2], px[3]
+		}
+		img.Pix[i*4+3] = 0xff
+	}
+	return img, nil
+}
+
+func (b *X11Backend) property(win xproto.Window, atom xproto.Atom) (*xproto.GetPropertyReply, error) {
+	return xproto.GetProperty(b.X, false, win, atom, xproto.GetPropertyTypeAny, 0, (1<<32)-1).Reply()
+}
+
+func (b *X11Backend) windowList(win xproto.Window, atom xproto.Atom) ([]WindowID, error) {
+	reply, err := b.property(win, atom)
+	if err != nil {
+		return nil, err
+	}
+	if reply.Format != 32 {
+		return nil, nil
+	}
+
+	ids := make([]WindowID, 0, len(reply.Value)/4)
+	for i := 0; i+4 <= len(reply.Value); i += 4 {
+		ids = append(ids, WindowID(xgb.Get32(reply.Value[i:])))
+	}
+	return ids, nil
+}
+
+func (b *X11Backend) windowTitle(win xproto.Window) (string, error) {
+	reply, err := b.property(win, b.atoms["_NET_WM_NAME"])
+	if err != nil {
+		return "", err
+	}
+	if len(reply.Value) > 0 {
+		return string(reply.Value), nil
+	}
+
+	// Fall back to the ICCCM title for clients that don't set _NET_WM_NAME.
+	reply, err = b.property(win, xproto.AtomWmName)
+	if err != nil {
+		return "", err
+	}
+	return string(reply.Value), nil
+}
+
+// windowClass returns the class half of WM_CLASS, which holds two
+// NUL-terminated strings: instance then class.
+func (b *X11Backend) windowClass(win xproto.Window) string {
+	reply, err := b.property(win, xproto.AtomWmClass)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.TrimRight(string(reply.Value), "\x00"), "\x00")
+	return parts[len(parts)-1]
+}
+
+func (b *X11Backend) windowDesktop(win xproto.Window) int {
+	reply, err := b.property(win, b.atoms["_NET_WM_DESKTOP"])
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return AllDesktops
+	}
+	desktop := xgb.Get32(reply.Value)
+	if desktop == 0xffffffff {
+		return AllDesktops
+	}
+	return int(desktop)
+}
+
+// windowState reads the _NET_WM_STATE flags we track. A window only counts
+// as maximized when it is maximized in both directions.
+func (b *X11Backend) windowState(win xproto.Window) WindowState {
+	reply, err := b.property(win, b.atoms["_NET_WM_STATE"])
+	if err != nil || reply.Format != 32 {
+		return 0
+	}
+	var state WindowState
+	maximized := 0
+	for i := 0; i+4 <= len(reply.Value); i += 4 {
+		switch xproto.Atom(xgb.Get32(reply.Value[i:])) {
+		case b.atoms["_NET_WM_STATE_HIDDEN"]:
+			state |= StateHidden
+		case b.atoms["_NET_WM_STATE_FULLSCREEN"]:
+			state |= StateFullscreen
+		case b.atoms["_NET_WM_STATE_DEMANDS_ATTENTION"]:
+			state |= StateDemandsAttention
+		case b.atoms["_NET_WM_STATE_MAXIMIZED_VERT"], b.atoms["_NET_WM_STATE_MAXIMIZED_HORZ"]:
+			maximized++
+		}
+	}
+	if maximized == 2 {
+		state |= StateMaximized
+	}
+	return state
+}
+
+// windowType returns the first window type we know the name of. Per EWMH,
+// windows without the property are "normal".
+func (b *X11Backend) windowType(win xproto.Window) string {
+	reply, err := b.property(win, b.atoms["_NET_WM_WINDOW_TYPE"])
+	if err != nil || reply.Format != 32 || len(reply.Value) < 4 {
+		return "normal"
+	}
+	for i := 0; i+4 <= len(reply.Value); i += 4 {
+		if t, ok := b.windowTypes[xproto.Atom(xgb.Get32(reply.Value[i:]))]; ok {
+			return t
+		}
+	}
+	return "normal"
+}
+
+// processName reads the command name from procfs, the same value ps prints
+// for "comm".
+func processName(pid int) (string, error) {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm")
+	if err != nil {
+		return "", fmt.Errorf("error getting application name: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
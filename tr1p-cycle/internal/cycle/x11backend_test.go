@@ -0,0 +1,27 @@
+package cycle
+
+import "testing"
+
+func TestIconSize(t *testing.T) {
+	tests := []struct {
+		name string
+		data []uint32
+		w, h int
+		ok   bool
+	}{
+		{"fits", []uint32{2, 2, 0, 0, 0, 0}, 2, 2, true},
+		{"short", []uint32{2, 2, 0, 0, 0}, 0, 0, false},
+		{"zero width", []uint32{0, 2}, 0, 0, false},
+		{"width too large", []uint32{7, 1, 0}, 0, 0, false},
+		{"product overflows", []uint32{0xffffffff, 0xffffffff, 0, 0}, 0, 0, false},
+		{"product too large", []uint32{3, 3, 0, 0, 0, 0, 0, 0}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, h, ok := iconSize(tt.data, 0)
+			if w != tt.w || h != tt.h || ok != tt.ok {
+				t.Errorf("iconSize = %d, %d, %v; want %d, %d, %v", w, h, ok, tt.w, tt.h, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Package dbusapi exports the rings on D-Bus so that desktop integrations
+// and status bars can control them and follow their changes.
+//
+// Every method takes a ring name; an empty name means the active ring.
+// Signals carry the ring name, the window ID and the window title.
+package dbusapi
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/tcornell05/go/tr1p-cycle/internal/cycle"
+)
+
+const (
+	BusName       = "io.github.tcornell05.Tr1pCycle"
+	InterfaceName = "io.github.tcornell05.Tr1pCycle"
+	ObjectPath    = dbus.ObjectPath("/io/github/tcornell05/Tr1pCycle")
+)
+
+// Item is one ring item as returned by List, a (usssb) struct on the bus.
+// Placeholders and ghosts have a zero Window.
+type Item struct {
+	Window  uint32
+	Title   string
+	AppName string
+	Class   string
+	Current bool
+}
+
+// object holds the methods exported on the bus. Every exported method on it
+// becomes a D-Bus method, so it has no others.
+type object struct {
+	ctl *cycle.Controller
+}
+
+func (o *object) Add(ring string) *dbus.Error {
+	return busError(o.ctl.Add(ring))
+}
+
+func (o *object) Remove(ring string) *dbus.Error {
+	return busError(o.ctl.Remove(ring))
+}
+
+func (o *object) Next(ring string) *dbus.Error {
+	return busError(o.ctl.Next(ring))
+}
+
+func (o *object) Prev(ring string) *dbus.Error {
+	return busError(o.ctl.Prev(ring))
+}
+
+func (o *object) List(ring string) ([]Item, *dbus.Error) {
+	infos, err := o.ctl.List(ring)
+	if err != nil {
+		return nil, busError(err)
+	}
+	items := make([]Item, len(infos))
+	for i, info := range infos {
+		items[i] = Item{
+			Window:  uint32(info.Window),
+			Title:   info.Title,
+			AppName: info.AppName,
+			Class:   info.Class,
+			Current: info.Current,
+		}
+	}
+	return items, nil
+}
+
+// FocusIndex focuses the item at the zero-based index in slot order.
+func (o *object) FocusIndex(ring string, index int32) *dbus.Error {
+	return busError(o.ctl.Focus(ring, int(index)))
+}
+
+func busError(err error) *dbus.Error {
+	if err == nil {
+		return nil
+	}
+	return dbus.MakeFailedError(err)
+}
+
+var signalArgs = []introspect.Arg{
+	{Name: "ring", Type: "s"},
+	{Name: "window", Type: "u"},
+	{Name: "title", Type: "s"},
+}
+
+var signalNames = map[cycle.RingEventKind]string{
+	cycle.ItemAdded:      "ItemAdded",
+	cycle.ItemRemoved:    "ItemRemoved",
+	cycle.CurrentChanged: "CurrentChanged",
+}
+
+// hasSignal reports whether ev is emitted as a signal.
+func hasSignal(ev cycle.RingEvent) bool {
+	_, ok := signalNames[ev.Kind]
+	return ok
+}
+
+// Export publishes ctl on conn under BusName and ObjectPath and emits a
+// signal for every change to rings. conn is normally the session bus, but
+// any connection works, such as one to a private bus.
+func Export(conn *dbus.Conn, ctl *cycle.Controller, rings *cycle.Rings) error {
+	obj := &object{ctl: ctl}
+	if err := conn.Export(obj, ObjectPath, InterfaceName); err != nil {
+		return fmt.Errorf("failed to export object: %v", err)
+	}
+
+	var signals []introspect.Signal
+	for _, kind := range []cycle.RingEventKind{cycle.ItemAdded, cycle.ItemRemoved, cycle.CurrentChanged} {
+		signals = append(signals, introspect.Signal{Name: signalNames[kind], Args: signalArgs})
+	}
+	node := &introspect.Node{
+		Name: string(ObjectPath),
+		Interfaces: []introspect.Interface{
+			introspect.IntrospectData,
+			{
+				Name:    InterfaceName,
+				Methods: introspect.Methods(obj),
+				Signals: signals,
+			},
+		},
+	}
+	if err := conn.Export(introspect.NewIntrospectable(node), ObjectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return fmt.Errorf("failed to export introspection data: %v", err)
+	}
+
+	reply, err := conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("failed to request name %s: %v", BusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("name %s is already taken", BusName)
+	}
+
+	// Ring events arrive with the ring locked, so they are emitted from a
+	// goroutine of their own, in order.
+	events := rings.SubscribeQueue("D-Bus signals", hasSignal)
+	go func() {
+		for ev := range events {
+			err := conn.Emit(ObjectPath, InterfaceName+"."+signalNames[ev.Kind], ev.Ring, uint32(ev.Window), ev.Title)
+			if err != nil {
+				log.Printf("Failed to emit %s: %v", signalNames[ev.Kind], err)
+			}
+		}
+	}()
+
+	log.Printf("Exported %s on D-Bus", BusName)
+	return nil
+}
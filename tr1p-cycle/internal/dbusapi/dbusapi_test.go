@@ -0,0 +1,135 @@
+package dbusapi
+
+import (
+	"bufio"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/tcornell05/go/tr1p-cycle/internal/cycle"
+)
+
+// privateBus starts a session bus of its own for the test and returns its
+// address. Tests that need it are skipped when dbus-daemon isn't installed.
+func privateBus(t *testing.T) string {
+	t.Helper()
+	path, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon not installed")
+	}
+	cmd := exec.Command(path, "--session", "--nofork", "--print-address")
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start dbus-daemon: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	addr, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatalf("failed to read bus address: %v", err)
+	}
+	return strings.TrimSpace(addr)
+}
+
+func connect(t *testing.T, addr string) *dbus.Conn {
+	t.Helper()
+	conn, err := dbus.Connect(addr)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// export serves a ring over windows 1 and 2, with window 1 active, and
+// returns the backend and a client connection.
+func export(t *testing.T) (*cycle.FakeBackend, *dbus.Conn) {
+	t.Helper()
+	addr := privateBus(t)
+
+	backend := cycle.NewFakeBackend(
+		cycle.WindowInfo{ID: 1, Title: "one", AppName: "xterm"},
+		cycle.WindowInfo{ID: 2, Title: "two", AppName: "firefox"},
+	)
+	rings := cycle.NewRings(backend, cycle.DefaultConfig())
+	ctl := cycle.NewController(rings, nil, func() {})
+	if err := Export(connect(t, addr), ctl, rings); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	return backend, connect(t, addr)
+}
+
+func TestMethods(t *testing.T) {
+	backend, client := export(t)
+	obj := client.Object(BusName, ObjectPath)
+
+	if err := obj.Call(InterfaceName+".Add", 0, "").Err; err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	backend.Focus(2)
+	if err := obj.Call(InterfaceName+".Add", 0, "").Err; err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	var items []Item
+	if err := obj.Call(InterfaceName+".List", 0, "").Store(&items); err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(items) != 2 || items[0].Title != "one" || items[1].Title != "two" || !items[0].Current {
+		t.Fatalf("List = %+v, want one then two, with one current", items)
+	}
+
+	if err := obj.Call(InterfaceName+".FocusIndex", 0, "", int32(1)).Err; err != nil {
+		t.Fatalf("FocusIndex: %v", err)
+	}
+	if win, _ := backend.ActiveWindow(); win.ID != 2 {
+		t.Errorf("active window after FocusIndex(1) = %s, want 2", win.ID)
+	}
+
+	errorCalls := []struct {
+		method string
+		args   []interface{}
+	}{
+		{"FocusIndex", []interface{}{"", int32(5)}},
+		{"Add", []interface{}{"missing"}},
+		{"List", []interface{}{"missing"}},
+	}
+	for _, c := range errorCalls {
+		if err := obj.Call(InterfaceName+"."+c.method, 0, c.args...).Err; err == nil {
+			t.Errorf("%s%v succeeded, want an error", c.method, c.args)
+		}
+	}
+}
+
+func TestSignals(t *testing.T) {
+	_, client := export(t)
+	if err := client.AddMatchSignal(dbus.WithMatchInterface(InterfaceName)); err != nil {
+		t.Fatal(err)
+	}
+	signals := make(chan *dbus.Signal, 10)
+	client.Signal(signals)
+
+	obj := client.Object(BusName, ObjectPath)
+	if err := obj.Call(InterfaceName+".Add", 0, "").Err; err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	select {
+	case sig := <-signals:
+		if sig.Name != InterfaceName+".ItemAdded" {
+			t.Fatalf("got signal %s, want ItemAdded", sig.Name)
+		}
+		if len(sig.Body) != 3 || sig.Body[0] != cycle.DefaultRingName || sig.Body[1] != uint32(1) || sig.Body[2] != "one" {
+			t.Errorf("ItemAdded body = %v", sig.Body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no signal received")
+	}
+}
@@ -0,0 +1,183 @@
+// Package ipc lets other processes control a running tr1p-cycle through a
+// Unix socket.
+//
+// Each connection carries one request and one response, both single lines
+// of JSON:
+//
+//	{"command": "focus", "ring": "code", "index": 0}
+//	{"items": [...]} or {"error": "..."}
+//
+// Commands are add, remove, next, prev, list, focus, save and quit. ring
+// may be left out to use the active ring; index is only used by focus and
+// is zero-based.
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tcornell05/go/tr1p-cycle/internal/cycle"
+)
+
+type Request struct {
+	Command string `json:"command"`
+	Ring    string `json:"ring,omitempty"`
+	Index   int    `json:"index,omitempty"`
+}
+
+type Response struct {
+	Error string           `json:"error,omitempty"`
+	Items []cycle.ItemInfo `json:"items,omitempty"`
+}
+
+// requestTimeout bounds how long a client may take to send its request.
+const requestTimeout = 5 * time.Second
+
+// responseTimeout bounds how long Send waits for an instance to answer.
+const responseTimeout = 10 * time.Second
+
+// DefaultSocketPath returns $XDG_RUNTIME_DIR/tr1p-cycle.sock, falling back
+// to a per-user name in the temporary directory.
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "tr1p-cycle.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("tr1p-cycle-%d.sock", os.Getuid()))
+}
+
+type Server struct {
+	ln   net.Listener
+	path string
+	ctl  *cycle.Controller
+}
+
+// Listen creates the control socket at path. A socket left behind by an
+// instance that is no longer running is replaced; a live one is an error.
+func Listen(path string, ctl *cycle.Controller) (*Server, error) {
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("another instance is already listening on %s", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket %s: %v", path, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %v", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to restrict %s: %v", path, err)
+	}
+	return &Server{ln: ln, path: path, ctl: ctl}, nil
+}
+
+// Serve handles connections until Close is called.
+func (s *Server) Serve() {
+	log.Printf("Listening for commands on %s", s.path)
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("Failed to accept control connection: %v", err)
+			continue
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops Serve and removes the socket.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	os.Remove(s.path)
+	return err
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(requestTimeout))
+
+	var req Request
+	var resp Response
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		log.Printf("Failed to read control request: %v", err)
+		return
+	}
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp.Error = fmt.Sprintf("invalid request: %v", err)
+	} else {
+		resp = s.handle(req)
+	}
+
+	if err := json.NewEncoder(conn).Encode(resp); err != nil {
+		log.Printf("Failed to write control response: %v", err)
+	}
+	if req.Command == "quit" {
+		s.ctl.Quit()
+	}
+}
+
+func (s *Server) handle(req Request) Response {
+	log.Printf("Control command: %s", req.Command)
+
+	var err error
+	var resp Response
+	switch req.Command {
+	case "add":
+		err = s.ctl.Add(req.Ring)
+	case "remove":
+		err = s.ctl.Remove(req.Ring)
+	case "next":
+		err = s.ctl.Next(req.Ring)
+	case "prev":
+		err = s.ctl.Prev(req.Ring)
+	case "list":
+		resp.Items, err = s.ctl.List(req.Ring)
+	case "focus":
+		err = s.ctl.Focus(req.Ring, req.Index)
+	case "save":
+		err = s.ctl.Save()
+	case "quit":
+		// Handled by serveConn once the response is written.
+	default:
+		err = fmt.Errorf("unknown command %q", req.Command)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+// Send delivers req to the instance listening on path and returns its
+// response. A response carrying an error is returned as an error.
+func Send(path string, req Request) (Response, error) {
+	conn, err := net.DialTimeout("unix", path, requestTimeout)
+	if err != nil {
+		return Response{}, fmt.Errorf("tr1p-cycle does not seem to be running: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(responseTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("failed to send request: %v", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return Response{}, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
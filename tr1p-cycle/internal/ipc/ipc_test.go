@@ -0,0 +1,102 @@
+package ipc
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tcornell05/go/tr1p-cycle/internal/cycle"
+)
+
+// serve listens on a socket in a temporary directory for a ring over
+// windows 1 to 3, with window 1 active, and returns the backend and the
+// socket path.
+func serve(t *testing.T) (*cycle.FakeBackend, string) {
+	t.Helper()
+	backend := cycle.NewFakeBackend(
+		cycle.WindowInfo{ID: 1, Title: "one", AppName: "xterm"},
+		cycle.WindowInfo{ID: 2, Title: "two", AppName: "firefox"},
+		cycle.WindowInfo{ID: 3, Title: "three", AppName: "emacs"},
+	)
+	cfg := cycle.DefaultConfig()
+	cfg.Rings = []cycle.RingConfig{{Name: "empty"}}
+	rings := cycle.NewRings(backend, cfg)
+	ctl := cycle.NewController(rings, nil, func() {})
+
+	path := filepath.Join(t.TempDir(), "control.sock")
+	srv, err := Listen(path, ctl)
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return backend, path
+}
+
+func TestRoundTrip(t *testing.T) {
+	backend, path := serve(t)
+	for _, id := range []cycle.WindowID{1, 2} {
+		backend.Focus(id)
+		if _, err := Send(path, Request{Command: "add"}); err != nil {
+			t.Fatalf("add window %s: %v", id, err)
+		}
+	}
+
+	resp, err := Send(path, Request{Command: "list"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(resp.Items) != 2 || resp.Items[0].Title != "one" || resp.Items[1].Title != "two" {
+		t.Fatalf("list = %+v, want one then two", resp.Items)
+	}
+
+	steps := []struct {
+		req  Request
+		want cycle.WindowID
+	}{
+		{Request{Command: "focus", Index: 0}, 1},
+		{Request{Command: "next"}, 2},
+		{Request{Command: "prev"}, 1},
+	}
+	for _, s := range steps {
+		if _, err := Send(path, s.req); err != nil {
+			t.Fatalf("%+v: %v", s.req, err)
+		}
+		if win, _ := backend.ActiveWindow(); win.ID != s.want {
+			t.Errorf("active window after %+v = %s, want %s", s.req, win.ID, s.want)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	backend, path := serve(t)
+	if _, err := Send(path, Request{Command: "add"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	backend.Close(1)
+	backend.Focus(3)
+
+	tests := []struct {
+		req  Request
+		want string
+	}{
+		{Request{Command: "dance"}, "unknown command"},
+		{Request{Command: "list", Ring: "missing"}, "no ring named"},
+		{Request{Command: "next", Ring: "empty"}, "is empty"},
+		{Request{Command: "prev", Ring: cycle.DefaultRingName}, "no open windows"},
+		{Request{Command: "focus", Ring: cycle.DefaultRingName, Index: 4}, "has no item"},
+		{Request{Command: "remove", Ring: cycle.DefaultRingName}, "is not in ring"},
+	}
+	for _, tt := range tests {
+		_, err := Send(path, tt.req)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: error %v, want one containing %q", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestSendWithoutInstance(t *testing.T) {
+	if _, err := Send(filepath.Join(t.TempDir(), "none.sock"), Request{Command: "list"}); err == nil {
+		t.Error("Send succeeded with nothing listening")
+	}
+}